@@ -0,0 +1,56 @@
+package lamenv
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BytesEncoding is the encoding used to decode and encode a []byte or a [N]byte from/into a single environment variable.
+type BytesEncoding string
+
+const (
+	// Base64 is the standard base64 encoding, as defined in RFC 4648. The padding is optional when decoding.
+	Base64 BytesEncoding = "base64"
+	// Base64URL is the alternate base64 encoding defined in RFC 4648, used in URLs and file names.
+	// The padding is optional when decoding.
+	Base64URL BytesEncoding = "base64url"
+	// Hex is the hexadecimal encoding.
+	Hex BytesEncoding = "hex"
+	// Raw means the value of the environment variable is used as it is.
+	Raw BytesEncoding = "raw"
+)
+
+func (e BytesEncoding) decode(input string) ([]byte, error) {
+	switch e {
+	case Base64:
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(input), "="))
+	case Base64URL:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(input), "="))
+	case Hex:
+		return hex.DecodeString(strings.TrimSpace(input))
+	case Raw:
+		return []byte(input), nil
+	}
+	return nil, fmt.Errorf("unknown bytes encoding %q", e)
+}
+
+func (e BytesEncoding) encode(b []byte) string {
+	switch e {
+	case Base64URL:
+		return base64.URLEncoding.EncodeToString(b)
+	case Hex:
+		return hex.EncodeToString(b)
+	case Raw:
+		return string(b)
+	default:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+// isBytes returns true if the type is a []byte or a [N]byte.
+func isBytes(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
@@ -0,0 +1,83 @@
+package lamenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytes(t *testing.T) {
+	type config struct {
+		Key    []byte            `yaml:"key"`
+		Hex    []byte            `yaml:"hex" lamenv:"hex"`
+		URL    []byte            `yaml:"url" lamenv:"base64url"`
+		Raw    []byte            `yaml:"raw" lamenv:"raw"`
+		Array  [4]byte           `yaml:"array" lamenv:"hex"`
+		Certs  map[string][]byte `yaml:"certs"`
+		Empty  []byte            `yaml:"empty"`
+		Hashes [][]byte          `yaml:"hashes"`
+	}
+	env := map[string]string{
+		"KEY":           "AQID/w==",
+		"HEX":           "0102ff",
+		"URL":           "AQID_w==",
+		"RAW":           "my secret",
+		"ARRAY":         "deadbeef",
+		"CERTS_MY_CERT": "Y2VydA==",
+		"HASHES_0":      "AQ==",
+		"HASHES_1":      "Ag==",
+	}
+	expected := &config{
+		Key:   []byte{1, 2, 3, 255},
+		Hex:   []byte{1, 2, 255},
+		URL:   []byte{1, 2, 3, 255},
+		Raw:   []byte("my secret"),
+		Array: [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Certs: map[string][]byte{
+			"my_cert": []byte("cert"),
+		},
+		Hashes: [][]byte{{1}, {2}},
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, expected, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, Marshal(expected, nil))
+	for k, v := range env {
+		assert.Equal(t, v, os.Getenv(k), k)
+		_ = os.Unsetenv(k)
+	}
+	_, exist := os.LookupEnv("EMPTY")
+	assert.False(t, exist)
+}
+
+func TestLamenv_BytesEncoding(t *testing.T) {
+	_ = os.Setenv("KEY", "0102")
+	defer os.Unsetenv("KEY")
+	c := &struct {
+		Key []byte
+	}{}
+	assert.NoError(t, New().BytesEncoding(Hex).Unmarshal(c, nil))
+	assert.Equal(t, []byte{1, 2}, c.Key)
+
+	// unpadded base64 is accepted
+	_ = os.Setenv("KEY", "AQID")
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, []byte{1, 2, 3}, c.Key)
+
+	_ = os.Setenv("KEY", "not hexadecimal")
+	assert.Error(t, New().BytesEncoding(Hex).Unmarshal(c, nil))
+
+	array := &struct {
+		Key [2]byte
+	}{}
+	_ = os.Setenv("KEY", "AQID")
+	assert.Error(t, Unmarshal(array, nil))
+}
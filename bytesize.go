@@ -0,0 +1,92 @@
+package lamenv
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that can be expressed with a SI (k, M, G, T, P, E) or an IEC (Ki, Mi, Gi, Ti, Pi, Ei)
+// suffix, like the quantities used by Kubernetes. The suffix can be followed by a B, and the number can be a decimal.
+// For example: 512Mi, 10MB, 1.5G, 2KiB, 1024.
+type ByteSize uint64
+
+var byteSizeType = reflect.TypeOf(ByteSize(0))
+
+// byteSizeNumber is the number expected before the unit. big.Rat accepts more, like a fraction or an exponent.
+var byteSizeNumber = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+type byteUnit struct {
+	suffix     string
+	multiplier uint64
+}
+
+// iecUnits and siUnits are sorted from the biggest unit to the smallest one
+var (
+	iecUnits = []byteUnit{
+		{suffix: "Ei", multiplier: 1 << 60},
+		{suffix: "Pi", multiplier: 1 << 50},
+		{suffix: "Ti", multiplier: 1 << 40},
+		{suffix: "Gi", multiplier: 1 << 30},
+		{suffix: "Mi", multiplier: 1 << 20},
+		{suffix: "Ki", multiplier: 1 << 10},
+	}
+	siUnits = []byteUnit{
+		{suffix: "E", multiplier: 1e18},
+		{suffix: "P", multiplier: 1e15},
+		{suffix: "T", multiplier: 1e12},
+		{suffix: "G", multiplier: 1e9},
+		{suffix: "M", multiplier: 1e6},
+		{suffix: "k", multiplier: 1e3},
+	}
+)
+
+// ParseByteSize parses a string like 512Mi, 10MB or 1.5G into a ByteSize.
+func ParseByteSize(s string) (ByteSize, error) {
+	input := strings.TrimSpace(s)
+	number := strings.TrimSuffix(input, "B")
+	multiplier := uint64(1)
+	for _, unit := range append(iecUnits, siUnits...) {
+		if strings.HasSuffix(number, unit.suffix) || (unit.suffix == "k" && strings.HasSuffix(number, "K")) {
+			number = number[:len(number)-len(unit.suffix)]
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number = strings.TrimSpace(number)
+	if !byteSizeNumber.MatchString(number) {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetUint64(multiplier))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid byte size %q, it is not a whole number of bytes", s)
+	}
+	if !r.Num().IsUint64() {
+		return 0, fmt.Errorf("invalid byte size %q, it is too large", s)
+	}
+	return ByteSize(r.Num().Uint64()), nil
+}
+
+// String returns the canonical form of the size, using the biggest IEC unit or else the biggest SI unit that
+// expresses the size as a whole number. If there is none, the size is expressed in bytes.
+func (b ByteSize) String() string {
+	size := uint64(b)
+	if size == 0 {
+		return "0"
+	}
+	for _, units := range [][]byteUnit{iecUnits, siUnits} {
+		for _, unit := range units {
+			if size%unit.multiplier == 0 {
+				return strconv.FormatUint(size/unit.multiplier, 10) + unit.suffix
+			}
+		}
+	}
+	return strconv.FormatUint(size, 10)
+}
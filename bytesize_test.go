@@ -0,0 +1,82 @@
+package lamenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseByteSize(t *testing.T) {
+	testSuites := []struct {
+		input  string
+		result ByteSize
+	}{
+		{input: "0", result: 0},
+		{input: "1024", result: 1024},
+		{input: "100B", result: 100},
+		{input: "512Mi", result: 512 << 20},
+		{input: "2KiB", result: 2048},
+		{input: "10MB", result: 10000000},
+		{input: "1.5G", result: 1500000000},
+		{input: "0.5Ki", result: 512},
+		{input: "1k", result: 1000},
+		{input: "1K", result: 1000},
+		{input: " 3 Gi ", result: 3 << 30},
+	}
+	for _, test := range testSuites {
+		t.Run(test.input, func(t *testing.T) {
+			size, err := ParseByteSize(test.input)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, size)
+		})
+	}
+	for _, input := range []string{"", "abc", "-1Mi", "1.1", "1Xi", "16Ei", "1/2Ki", "1e3", "0x10", ".5Ki", "1.Ki", "+1Ki"} {
+		_, err := ParseByteSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	testSuites := []struct {
+		size   ByteSize
+		result string
+	}{
+		{size: 0, result: "0"},
+		{size: 100, result: "100"},
+		{size: 1536, result: "1536"},
+		{size: 512 << 20, result: "512Mi"},
+		{size: 1500000000, result: "1500M"},
+		{size: 10000000, result: "10M"},
+		{size: 1 << 60, result: "1Ei"},
+	}
+	for _, test := range testSuites {
+		t.Run(test.result, func(t *testing.T) {
+			assert.Equal(t, test.result, test.size.String())
+			size, err := ParseByteSize(test.result)
+			assert.NoError(t, err)
+			assert.Equal(t, test.size, size)
+		})
+	}
+}
+
+func TestByteSizeField(t *testing.T) {
+	type config struct {
+		Memory ByteSize  `yaml:"memory"`
+		Buffer *ByteSize `yaml:"buffer"`
+	}
+	_ = os.Setenv("MEMORY", "512Mi")
+	_ = os.Setenv("BUFFER", "1.5k")
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	buffer := ByteSize(1500)
+	assert.Equal(t, &config{Memory: 512 << 20, Buffer: &buffer}, c)
+	_ = os.Unsetenv("MEMORY")
+	_ = os.Unsetenv("BUFFER")
+
+	assert.NoError(t, Marshal(c, nil))
+	assert.Equal(t, "512Mi", os.Getenv("MEMORY"))
+	assert.Equal(t, "1500", os.Getenv("BUFFER"))
+	_ = os.Unsetenv("MEMORY")
+	_ = os.Unsetenv("BUFFER")
+}
@@ -2,7 +2,9 @@ package lamenv
 
 import (
 	"encoding"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -13,11 +15,23 @@ const (
 	omitempty = "omitempty"
 	squash    = "squash"
 	inline    = "inline"
+	// flagTag is the tag holding the flags specific to lamenv, like "replace". They are not read from the tags like "yaml",
+	// since the libraries reading these tags usually refuse a flag they don't know.
+	flagTag = "lamenv"
 )
 
-var durationType = reflect.TypeOf(time.Duration(0))
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
 
-func (l *Lamenv) decode(conf reflect.Value, parts []string) error {
+// decode is looking at the type of conf to know how to decode it.
+// flags is the list of options coming from the tag of the field (if any) that is decoded.
+func (l *Lamenv) decode(conf reflect.Value, parts []string, flags []string) error {
+	if decoder, ok := l.decoders[conf.Type()]; ok {
+		return l.decodeWithDecoder(conf, parts, decoder)
+	}
 	v := conf
 	// ptr will be used to try if the value is implementing the interface Unmarshaler.
 	// if it's the case then, the implementation of the interface has the priority.
@@ -35,6 +49,19 @@ func (l *Lamenv) decode(conf reflect.Value, parts []string) error {
 		ptr.Elem().Set(v)
 	}
 
+	if decoder, ok := l.decoders[v.Type()]; ok {
+		return l.decodeWithDecoder(v, parts, decoder)
+	}
+
+	if p, ok := ptr.Interface().(DecoderUnmarshaler); ok {
+		if err := p.UnmarshalEnvFrom(&Decoder{l: l, parts: parts}); err != nil {
+			return err
+		}
+		// in case the method UnmarshalEnvFrom() is setting some parameter in the struct, we have to save these changes
+		v.Set(ptr.Elem())
+		return nil
+	}
+
 	if p, ok := ptr.Interface().(Unmarshaler); ok {
 		if err := p.UnmarshalEnv(parts); err != nil {
 			return err
@@ -45,9 +72,9 @@ func (l *Lamenv) decode(conf reflect.Value, parts []string) error {
 	}
 
 	if p, ok := ptr.Interface().(encoding.TextUnmarshaler); ok {
-		if variable, input, exist := lookupEnv(parts); exist {
+		if variable, input, exist := l.lookupEnv(parts); exist {
 			// remove the variable to avoid reusing it later
-			delete(l.env, variable)
+			l.markUsed(variable)
 			if err := p.UnmarshalText([]byte(input)); err != nil {
 				return err
 			}
@@ -58,30 +85,116 @@ func (l *Lamenv) decode(conf reflect.Value, parts []string) error {
 		return nil
 	}
 
+	if isBytes(v.Type()) {
+		return l.decodeBytes(v, parts, flags)
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+		switch l.mergeStrategyOf(flags) {
+		case Replace:
+			if l.containsVariable(parts) {
+				v.Set(reflect.Zero(v.Type()))
+			}
+		case Append:
+			if v.Kind() == reflect.Slice && v.Len() > 0 {
+				return l.decodeAppend(v, parts, flags)
+			}
+		}
+	}
+
+	if ok, forced := l.isJSONValue(v.Type(), flags); ok {
+		// the JSON document is decoded first, so the other variables can override it
+		if err := l.decodeJSON(v, parts, forced); err != nil {
+			return err
+		}
+	}
+
 	switch v.Kind() {
 	case reflect.Map:
-		if err := l.decodeMap(v, parts); err != nil {
+		if err := l.decodeMap(v, parts, flags); err != nil {
 			return err
 		}
 	case reflect.Slice:
-		if err := l.decodeSlice(v, parts); err != nil {
+		if err := l.decodeSlice(v, parts, flags); err != nil {
 			return err
 		}
 	case reflect.Struct:
 		if err := l.decodeStruct(v, parts); err != nil {
 			return err
 		}
+	case reflect.Interface:
+		if registration, ok := l.interfaces[v.Type()]; ok {
+			if err := l.decodeRegisteredInterface(v, parts, registration); err != nil {
+				return err
+			}
+			return nil
+		}
+		if v.NumMethod() > 0 {
+			// there is no way to know which concrete type should be used
+			return nil
+		}
+		if err := l.decodeInterface(v, parts); err != nil {
+			return err
+		}
 	default:
-		if variable, input, exist := lookupEnv(parts); exist {
+		if variable, input, exist := l.lookupEnv(parts); exist {
 			// remove the variable to avoid to reuse it later
-			delete(l.env, variable)
-			return l.decodeNative(v, input)
+			l.markUsed(variable)
+			if err := l.decodeNative(v, input); err != nil {
+				return fmt.Errorf("unable to decode the variable %s: %w", variable, err)
+			}
 		}
 	}
 	return nil
 }
 
+// decodeAppend decodes the environment variables into a new slice that is then appended to the current one.
+func (l *Lamenv) decodeAppend(v reflect.Value, parts []string, flags []string) error {
+	items := reflect.New(v.Type()).Elem()
+	if err := l.decode(items, parts, flags); err != nil {
+		return err
+	}
+	v.Set(reflect.AppendSlice(v, items))
+	return nil
+}
+
+// decodeWithDecoder uses a decoder registered by the user to decode the environment variable.
+func (l *Lamenv) decodeWithDecoder(v reflect.Value, parts []string, decoder DecodeFunc) error {
+	variable, input, exist := l.lookupEnv(parts)
+	if !exist {
+		return nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	result, err := decoder(input)
+	if err != nil {
+		return fmt.Errorf("unable to decode the variable %s: %w", variable, err)
+	}
+	if result == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	value := reflect.ValueOf(result)
+	if !value.Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("unable to decode the variable %s: the decoder returned a %s instead of a %s", variable, value.Type(), v.Type())
+	}
+	v.Set(value)
+	return nil
+}
+
 func (l *Lamenv) decodeNative(v reflect.Value, input string) error {
+	for _, hook := range l.decodeHooks {
+		result, err := hook(input, v.Type())
+		if err != nil {
+			return err
+		}
+		if s, isString := result.(string); isString {
+			input = s
+			continue
+		}
+		// the hook already did the job of parsing the input, so there is nothing else to do than setting the value
+		return setHookResult(v, result)
+	}
 	switch v.Kind() {
 	case reflect.String:
 		l.decodeString(v, input)
@@ -110,10 +223,32 @@ func (l *Lamenv) decodeNative(v reflect.Value, input string) error {
 		if err := l.decodeFloat(v, input); err != nil {
 			return err
 		}
+	case reflect.Complex64,
+		reflect.Complex128:
+		if err := l.decodeComplex(v, input); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func setHookResult(v reflect.Value, result interface{}) error {
+	if result == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	value := reflect.ValueOf(result)
+	if value.Type().AssignableTo(v.Type()) {
+		v.Set(value)
+		return nil
+	}
+	if value.Type().ConvertibleTo(v.Type()) {
+		v.Set(value.Convert(v.Type()))
+		return nil
+	}
+	return fmt.Errorf("unable to set the %s returned by a decode hook into a %s", value.Type(), v.Type())
+}
+
 func (l *Lamenv) decodeString(v reflect.Value, input string) {
 	v.SetString(input)
 }
@@ -135,9 +270,10 @@ func (l *Lamenv) decodeInt(v reflect.Value, input string) error {
 		}
 		v.SetInt(int64(i))
 	} else {
-		i, err := strconv.ParseInt(strings.TrimSpace(input), 10, 0)
+		// base 0 means the base is guessed from the prefix (0x, 0o, 0b) and underscores are accepted
+		i, err := strconv.ParseInt(integerInput(input), 0, v.Type().Bits())
 		if err != nil {
-			return err
+			return parseIntError(v, input, err)
 		}
 		v.SetInt(i)
 	}
@@ -146,16 +282,53 @@ func (l *Lamenv) decodeInt(v reflect.Value, input string) error {
 }
 
 func (l *Lamenv) decodeUInt(v reflect.Value, input string) error {
-	i, err := strconv.ParseUint(strings.TrimSpace(input), 10, 0)
+	if v.Type() == byteSizeType {
+		size, err := ParseByteSize(input)
+		if err != nil {
+			return err
+		}
+		v.SetUint(uint64(size))
+		return nil
+	}
+	i, err := strconv.ParseUint(integerInput(input), 0, v.Type().Bits())
 	if err != nil {
-		return err
+		return parseIntError(v, input, err)
 	}
 	v.SetUint(i)
 	return nil
 }
 
+// integerInput prepares the input to be parsed with the base 0, which is guessing the base from the prefix 0x, 0o or 0b.
+// Without such a prefix, the number must stay a decimal one. But the base 0 is also considering a number starting
+// by 0 as an octal one, like in Go. That's why the leading zeros are removed: 010 is 10 and not 8.
+func integerInput(input string) string {
+	trimmedInput := strings.TrimSpace(input)
+	sign := ""
+	if strings.HasPrefix(trimmedInput, "-") || strings.HasPrefix(trimmedInput, "+") {
+		sign = trimmedInput[:1]
+		trimmedInput = trimmedInput[1:]
+	}
+	if len(trimmedInput) > 1 && trimmedInput[0] == '0' {
+		switch trimmedInput[1] {
+		case 'x', 'X', 'o', 'O', 'b', 'B':
+			return sign + trimmedInput
+		}
+	}
+	for len(trimmedInput) > 1 && trimmedInput[0] == '0' && (trimmedInput[1] >= '0' && trimmedInput[1] <= '9' || trimmedInput[1] == '_') {
+		trimmedInput = trimmedInput[1:]
+	}
+	return sign + trimmedInput
+}
+
+func parseIntError(v reflect.Value, input string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("the value %q overflows %s", input, v.Type())
+	}
+	return err
+}
+
 func (l *Lamenv) decodeFloat(v reflect.Value, input string) error {
-	i, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	i, err := strconv.ParseFloat(strings.TrimSpace(input), v.Type().Bits())
 	if err != nil {
 		return err
 	}
@@ -163,17 +336,49 @@ func (l *Lamenv) decodeFloat(v reflect.Value, input string) error {
 	return nil
 }
 
-// decodeSlice will support ony one syntax which is:
+func (l *Lamenv) decodeComplex(v reflect.Value, input string) error {
+	c, err := strconv.ParseComplex(strings.TrimSpace(input), v.Type().Bits())
+	if err != nil {
+		return err
+	}
+	v.SetComplex(c)
+	return nil
+}
+
+// decodeSlice will support by default ony one syntax which is:
 //
 //	<PREFIX>_<SLICE_INDEX>(_<SUFFIX>)?
 //
 // This syntax is the only one that is able to manage smoothly every existing type in Golang and it is a determinist syntax.
-func (l *Lamenv) decodeSlice(v reflect.Value, parts []string) error {
+//
+// When it's enabled, a slice of scalar can also be provided as a single delimited variable: <PREFIX>=<ITEM>,<ITEM>.
+// In this case, the delimited variable replaces the current content of the slice
+// and then the indexed variables (if any) override the items by index.
+func (l *Lamenv) decodeSlice(v reflect.Value, parts []string, flags []string) error {
 	sliceType := v.Type().Elem()
+	delimited := false
+	if l.isDelimitedList(v.Type(), flags) {
+		var err error
+		if delimited, err = l.decodeDelimitedList(v, parts); err != nil {
+			return err
+		}
+	}
+	length, err := l.decodeSliceLength(parts)
+	if err != nil {
+		return err
+	}
+	if length >= 0 || l.sliceGapPolicy != StopAtGap {
+		return l.decodeSparseSlice(v, parts, length)
+	}
 	// While we are able to find an environment variable that is starting by <PREFIX>_<SLICE_INDEX>
 	// then it will create a new item in a slice and will use the next recursive loop to set it.
 	i := 0
-	for ok := contains(append(parts, strconv.Itoa(i))); ok; ok = contains(append(parts, strconv.Itoa(i))) {
+	for ok := l.contains(append(parts, strconv.Itoa(i))); ok || (delimited && i < v.Len()); ok = l.contains(append(parts, strconv.Itoa(i))) {
+		if !ok {
+			// the item is coming from the delimited variable and no indexed variable overrides it
+			i++
+			continue
+		}
 		var sliceElem reflect.Value
 		if i < v.Len() {
 			// that means there is already an element in the slice and should just complete or override the value
@@ -182,7 +387,7 @@ func (l *Lamenv) decodeSlice(v reflect.Value, parts []string) error {
 			// in that case we have to create a new element
 			sliceElem = reflect.Indirect(reflect.New(sliceType))
 		}
-		if err := l.decode(sliceElem, append(parts, strconv.Itoa(i))); err != nil {
+		if err := l.decode(sliceElem, append(parts, strconv.Itoa(i)), nil); err != nil {
 			return err
 		}
 
@@ -195,6 +400,128 @@ func (l *Lamenv) decodeSlice(v reflect.Value, parts []string) error {
 	return nil
 }
 
+// decodeSliceLength returns the length declared by the variable <PREFIX>_LEN, or -1 if it's not declared.
+func (l *Lamenv) decodeSliceLength(parts []string) (int, error) {
+	if !l.sliceLength {
+		return -1, nil
+	}
+	variable, input, exist := l.lookupEnv(append(parts, sliceLengthSuffix))
+	if !exist {
+		return -1, nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	length, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || length < 0 {
+		return -1, fmt.Errorf("unable to decode the variable %s: %q is not a valid length", variable, input)
+	}
+	if length > l.maxSliceLength {
+		return -1, fmt.Errorf("unable to decode the variable %s: the length %d exceeds the maximum length %d of a slice", variable, length, l.maxSliceLength)
+	}
+	return length, nil
+}
+
+// decodeSparseSlice is looking at every index available and not only at the ones following each other from 0.
+// Then, the gaps between the indexes are treated according to the policy chosen.
+// If the length is declared (i.e. >= 0), the slice is resized to this length and no index can exceed it.
+func (l *Lamenv) decodeSparseSlice(v reflect.Value, parts []string, length int) error {
+	variable := l.buildEnvVariable(parts)
+	indexes := l.findIndexes(parts)
+	if l.sliceGapPolicy == ErrorOnGap {
+		for position, index := range indexes {
+			if position != index {
+				return fmt.Errorf("the index %d of the variable %s is missing", position, variable)
+			}
+		}
+	}
+	if l.sliceGapPolicy == StopAtGap && length < 0 {
+		// only keep the indexes following each other from 0. When the length is declared, the missing indexes are
+		// left to the zero value instead.
+		for position, index := range indexes {
+			if position != index {
+				indexes = indexes[:position]
+				break
+			}
+		}
+	}
+	// positions is where each index will be decoded in the slice
+	positions := make([]int, len(indexes))
+	size := 0
+	for position, index := range indexes {
+		positions[position] = index
+		if l.sliceGapPolicy == CompactGaps {
+			positions[position] = position
+		}
+		size = positions[position] + 1
+	}
+	if size > l.maxSliceLength {
+		return fmt.Errorf("unable to decode the variable %s: the index %d exceeds the maximum length %d of a slice", variable, indexes[len(indexes)-1], l.maxSliceLength)
+	}
+	if length >= 0 {
+		if size > length {
+			return fmt.Errorf("the index %d of the variable %s exceeds the declared length %d", indexes[len(indexes)-1], variable, length)
+		}
+		size = length
+		if v.Len() > length {
+			v.Set(v.Slice(0, length))
+		}
+	}
+	if v.Len() < size {
+		v.Set(reflect.AppendSlice(v, reflect.MakeSlice(v.Type(), size-v.Len(), size-v.Len())))
+	}
+	for i, index := range indexes {
+		if err := l.decode(v.Index(positions[i]), append(parts, strconv.Itoa(index)), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBytes decodes a single environment variable into a []byte or a [N]byte, using the encoding chosen.
+func (l *Lamenv) decodeBytes(v reflect.Value, parts []string, flags []string) error {
+	variable, input, exist := l.lookupEnv(parts)
+	if !exist {
+		return nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	b, err := l.bytesEncodingOf(flags).decode(input)
+	if err != nil {
+		return fmt.Errorf("unable to decode the variable %s: %w", variable, err)
+	}
+	if v.Kind() == reflect.Array {
+		if len(b) != v.Len() {
+			return fmt.Errorf("unable to decode the variable %s: %d bytes are expected, got %d", variable, v.Len(), len(b))
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+		return nil
+	}
+	v.SetBytes(b)
+	return nil
+}
+
+// decodeDelimitedList decodes the variable <PREFIX> containing the items of the slice. It returns true if the variable exists.
+func (l *Lamenv) decodeDelimitedList(v reflect.Value, parts []string) (bool, error) {
+	variable, input, exist := l.lookupEnv(parts)
+	if !exist {
+		return false, nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	var items []string
+	if len(input) > 0 {
+		items = splitEscaped(input, l.listSeparator, -1)
+	}
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := l.decodeScalar(slice.Index(i), unescape(item)); err != nil {
+			return true, fmt.Errorf("unable to decode the item %d of the variable %s: %w", i, variable, err)
+		}
+	}
+	v.Set(slice)
+	return true, nil
+}
+
 func (l *Lamenv) decodeStruct(v reflect.Value, parts []string) error {
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
@@ -212,7 +539,7 @@ func (l *Lamenv) decodeStruct(v reflect.Value, parts []string) error {
 				continue
 			}
 			if containStr(tags, squash) || containStr(tags, inline) {
-				if err := l.decode(field, parts); err != nil {
+				if err := l.decode(field, parts, lookupFlags(fieldType.Tag)); err != nil {
 					return err
 				}
 				continue
@@ -224,71 +551,341 @@ func (l *Lamenv) decodeStruct(v reflect.Value, parts []string) error {
 				// To be more accurate, we would have to check the type of the field, because if it's a native type, then we will have to check if the parts are matching an environment variable.
 				// If it's a struct or an array or a map, then we will have to check if there is at least one variable starting by the parts + "_" (which would remove the possibility of having a field being a prefix of another one)
 				// So it's simpler like that. Let's see if I'm wrong or not.
-				if !contains(append(parts, fieldName)) {
+				if !l.contains(append(parts, fieldName)) {
 					continue
 				}
 			}
 		} else {
 			fieldName = fieldType.Name
 		}
-		if err := l.decode(field, append(parts, fieldName)); err != nil {
+		if err := l.decode(field, append(parts, fieldName), lookupFlags(fieldType.Tag)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (l *Lamenv) decodeMap(v reflect.Value, parts []string) error {
+func (l *Lamenv) decodeMap(v reflect.Value, parts []string, flags []string) error {
 	keyType := v.Type().Key()
 	valueType := v.Type().Elem()
-	if keyType.Kind() != reflect.String {
-		return fmt.Errorf("unable to unmarshal a map with a key that is not a string")
-	}
-	if valueType.Kind() == reflect.Map {
-		return fmt.Errorf("unable to unmarshal a map of a map, it's not a determinist datamodel")
+	if !isSupportedMapKey(keyType) {
+		return fmt.Errorf("unable to unmarshal a map with a key of type %s", keyType)
 	}
 	valMap := v
 	if v.IsNil() {
 		mapType := reflect.MapOf(keyType, valueType)
 		valMap = reflect.MakeMap(mapType)
 	}
+	if l.isInlineMap(v.Type(), flags) {
+		// the inline variable is decoded first, so the variables per key can override it.
+		if err := l.decodeInlineMap(valMap, parts); err != nil {
+			return err
+		}
+	}
+	if valueType.Kind() == reflect.Interface && valueType.NumMethod() == 0 {
+		// there is no type to look at to guess the key, so every segment of the environment variable is considered as a key.
+		if err := l.decodeSchemalessMap(valMap, parts); err != nil {
+			return err
+		}
+		v.Set(valMap)
+		return nil
+	}
 	// The main issue with the map when you are dealing with environment variable is to be able to find the key of the map
 	// A way to achieve it is to take a look at the type of the value of the map.
 	// It will be used to find every potential future parts, which will be then used as a variable suffix.
 	// Like that we are able catch the key that would be in the middle of the prefix parts and the future parts
 
 	// Let's create first the struct that would represent what is behind the value of the map
-	parser := newRing(valueType, l.tagSupports)
+	parser := newRing(valueType, l)
+	mapLevel := parser.isMapLevel(valueType)
 
 	// then foreach environment variable:
 	// 1. Remove the prefix parts
 	// 2. Pass the remaining parts to the parser that would return the prefix to be used.
 	for e := range l.env {
-		variable := buildEnvVariable(parts)
-		trimEnv := strings.TrimPrefix(e, variable+"_")
+		variable := l.buildEnvVariable(parts)
+		trimEnv := strings.TrimPrefix(e, variable+l.separator)
 		if trimEnv == e {
 			// TrimPrefix didn't remove anything, so that means, the environment variable doesn't start with the prefix parts
 			continue
 		}
-		futureParts := strings.Split(trimEnv, "_")
-		prefix, err := guessPrefix(futureParts, parser)
-		if err != nil {
-			return err
+		futureParts := strings.Split(trimEnv, l.separator)
+		var prefix string
+		if mapLevel {
+			prefix = guessMapKey(futureParts, l.separator)
+		} else {
+			var err error
+			prefix, err = guessPrefix(futureParts, parser, l.separator)
+			if err != nil {
+				return err
+			}
 		}
 		if len(prefix) == 0 {
 			// no prefix find, let's move to the next environment
 			continue
 		}
-		keyString := strings.ToLower(prefix)
+		key, err := l.decodeMapKey(keyType, strings.TrimSpace(l.mapKeyCase(prefix)))
+		if err != nil {
+			return err
+		}
+		l.logf("the key %s of the map %s is guessed from the variable %s", prefix, variable, e)
+		valueParts := append(parts, prefix)
+		if mapLevel {
+			// the key of the next map is preceded by an empty part
+			valueParts = append(valueParts, "")
+		}
 		value := reflect.Indirect(reflect.New(valueType))
-		if err := l.decode(value, append(parts, keyString)); err != nil {
+		if err := l.decode(value, valueParts, nil); err != nil {
 			return err
 		}
-		key := reflect.Indirect(reflect.New(reflect.TypeOf("")))
-		key.SetString(strings.TrimSpace(strings.ToLower(keyString)))
 		valMap.SetMapIndex(key, value)
 	}
 	// Set the built up map to the value
 	v.Set(valMap)
 	return nil
 }
+
+// decodeInlineMap decodes a single variable containing the pairs of key/value: <PREFIX>=<KEY>=<VALUE>,<KEY>=<VALUE>
+// Since the key is in the value of the variable, its casing is kept and the KeyCase policy is not applied.
+func (l *Lamenv) decodeInlineMap(v reflect.Value, parts []string) error {
+	variable, input, exist := l.lookupEnv(parts)
+	if !exist {
+		return nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	if len(input) == 0 {
+		return nil
+	}
+	for _, pair := range splitEscaped(input, l.mapPairSeparator, -1) {
+		keyValue := splitEscaped(pair, l.mapKeyValueSeparator, 2)
+		if len(keyValue) != 2 {
+			return fmt.Errorf("unable to decode the variable %s: %q is not a pair of key/value", variable, unescape(pair))
+		}
+		key, err := l.decodeMapKey(v.Type().Key(), strings.TrimSpace(unescape(keyValue[0])))
+		if err != nil {
+			return err
+		}
+		value := reflect.New(v.Type().Elem()).Elem()
+		if err := l.decodeScalar(value, unescape(keyValue[1])); err != nil {
+			return fmt.Errorf("unable to decode the variable %s: %w", variable, err)
+		}
+		v.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// decodeMapKey parses the key guessed from the environment variable into the type of the key of the map.
+func (l *Lamenv) decodeMapKey(keyType reflect.Type, input string) (reflect.Value, error) {
+	key := reflect.New(keyType)
+	if p, ok := key.Interface().(encoding.TextUnmarshaler); ok {
+		if err := p.UnmarshalText([]byte(input)); err != nil {
+			return reflect.Value{}, err
+		}
+		return key.Elem(), nil
+	}
+	if err := l.decodeNative(key.Elem(), input); err != nil {
+		return reflect.Value{}, fmt.Errorf("unable to decode the key '%s' of the map: %w", input, err)
+	}
+	return key.Elem(), nil
+}
+
+// isSupportedMapKey returns true if the type can be used as a key of a map when unmarshalling it.
+func isSupportedMapKey(keyType reflect.Type) bool {
+	if reflect.PtrTo(keyType).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch keyType.Kind() {
+	case reflect.String,
+		reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// decodeRegisteredInterface uses the discriminator to know which concrete type must be used to decode the interface.
+// If the discriminator is not set, then the concrete value already present (if any) is completed.
+func (l *Lamenv) decodeRegisteredInterface(v reflect.Value, parts []string, registration *interfaceRegistration) error {
+	var concreteType reflect.Type
+	if variable, input, exist := l.lookupEnv(append(parts, registration.discriminator)); exist {
+		// remove the variable to avoid reusing it later
+		l.markUsed(variable)
+		t, ok := registration.types[strings.TrimSpace(input)]
+		if !ok {
+			return fmt.Errorf("unable to decode the variable %s, the type %q is not registered for the interface %s", variable, input, v.Type())
+		}
+		concreteType = t
+	} else if !v.IsNil() {
+		concreteType = v.Elem().Type()
+	} else {
+		return nil
+	}
+	value := reflect.New(concreteType).Elem()
+	if !v.IsNil() && v.Elem().Type() == concreteType {
+		// keep what is already set in the concrete value
+		value.Set(v.Elem())
+	}
+	if err := l.decode(value, parts, nil); err != nil {
+		return err
+	}
+	v.Set(value)
+	return nil
+}
+
+// schemalessNode is the tree built from the environment variables when the type to decode is not known.
+// A node is either holding a value or a list of children. It cannot be both.
+type schemalessNode struct {
+	value    *string
+	children map[string]*schemalessNode
+}
+
+// decodeInterface decodes every environment variable starting with the parts into a tree made of
+// map[string]interface{}, []interface{} and scalar values.
+// Segments that are all numbers are considered as indexes of a slice, the other ones as keys of a map.
+func (l *Lamenv) decodeInterface(v reflect.Value, parts []string) error {
+	tree, err := l.buildSchemalessTree(parts)
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		return nil
+	}
+	v.Set(reflect.ValueOf(l.schemalessValue(tree)))
+	return nil
+}
+
+func (l *Lamenv) decodeSchemalessMap(v reflect.Value, parts []string) error {
+	tree, err := l.buildSchemalessTree(parts)
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		return nil
+	}
+	if tree.value != nil {
+		return fmt.Errorf("unable to unmarshal the variable %s into a map", l.buildEnvVariable(parts))
+	}
+	for segment, child := range tree.children {
+		key, err := l.decodeMapKey(v.Type().Key(), strings.TrimSpace(l.mapKeyCase(segment)))
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(key, reflect.ValueOf(l.schemalessValue(child)))
+	}
+	return nil
+}
+
+// buildSchemalessTree returns nil when there is no environment variable starting with the parts.
+func (l *Lamenv) buildSchemalessTree(parts []string) (*schemalessNode, error) {
+	var tree *schemalessNode
+	variable := l.buildEnvVariable(parts)
+	for e := range l.env {
+		var segments []string
+		if e != variable {
+			trimEnv := e
+			if len(variable) > 0 {
+				trimEnv = strings.TrimPrefix(e, variable+l.separator)
+				if trimEnv == e {
+					continue
+				}
+			}
+			for _, segment := range strings.Split(trimEnv, l.separator) {
+				if len(segment) > 0 {
+					segments = append(segments, segment)
+				}
+			}
+		}
+		value, exist := l.source.Lookup(e)
+		if !exist {
+			continue
+		}
+		// remove the variable to avoid reusing it later
+		l.markUsed(e)
+		if tree == nil {
+			tree = &schemalessNode{}
+		}
+		node := tree
+		for _, segment := range segments {
+			if node.value != nil {
+				return nil, fmt.Errorf("the variable %s is conflicting with another variable defining a value for a parent key", e)
+			}
+			if node.children == nil {
+				node.children = make(map[string]*schemalessNode)
+			}
+			child, ok := node.children[segment]
+			if !ok {
+				child = &schemalessNode{}
+				node.children[segment] = child
+			}
+			node = child
+		}
+		if node.children != nil {
+			return nil, fmt.Errorf("the variable %s is conflicting with other variables defining some keys under it", e)
+		}
+		node.value = &value
+	}
+	return tree, nil
+}
+
+func (l *Lamenv) schemalessValue(node *schemalessNode) interface{} {
+	if node.value != nil {
+		if l.inferScalarTypes {
+			return inferScalar(*node.value)
+		}
+		return *node.value
+	}
+	maxIndex := -1
+	for segment := range node.children {
+		index, err := strconv.Atoi(segment)
+		// an index like 01 is not considered as an index, otherwise it would be conflicting with the index 1
+		if err != nil || index < 0 || strconv.Itoa(index) != segment {
+			maxIndex = -1
+			break
+		}
+		if index > maxIndex {
+			maxIndex = index
+		}
+	}
+	// The slice is only built when the indexes are dense enough: there must be at least as many indexes as missing ones.
+	// Otherwise, a single variable with a huge index would be enough to allocate a huge slice.
+	if maxIndex >= 0 && maxIndex < 2*len(node.children) {
+		// every segment is an index, so it's a slice. Missing indexes are left to nil.
+		result := make([]interface{}, maxIndex+1)
+		for segment, child := range node.children {
+			index, _ := strconv.Atoi(segment)
+			result[index] = l.schemalessValue(child)
+		}
+		return result
+	}
+	result := make(map[string]interface{}, len(node.children))
+	for segment, child := range node.children {
+		result[strings.TrimSpace(l.mapKeyCase(segment))] = l.schemalessValue(child)
+	}
+	return result
+}
+
+// inferScalar returns the input as an int, a float64 or a bool if it is possible to parse it as such.
+// Otherwise, the input is returned as it is.
+func inferScalar(input string) interface{} {
+	trimmedInput := strings.TrimSpace(input)
+	if i, err := strconv.ParseInt(trimmedInput, 10, 0); err == nil {
+		return int(i)
+	}
+	if f, err := strconv.ParseFloat(trimmedInput, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	if strings.EqualFold(trimmedInput, "true") || strings.EqualFold(trimmedInput, "false") {
+		return strings.EqualFold(trimmedInput, "true")
+	}
+	return input
+}
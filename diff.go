@@ -0,0 +1,121 @@
+package lamenv
+
+import (
+	"reflect"
+	"sort"
+)
+
+const (
+	sensitive     = "sensitive"
+	sensitiveMask = "******"
+)
+
+// ChangeKind is the kind of change made on an environment variable between two values.
+type ChangeKind int
+
+const (
+	// Added means the variable is only produced by the new value.
+	Added ChangeKind = iota
+	// Removed means the variable is only produced by the old value.
+	Removed
+	// Modified means the variable is produced by both values, but with a different content.
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// VariableChange is a change made on an environment variable between two values.
+type VariableChange struct {
+	Variable string
+	Kind     ChangeKind
+	// Old is the content of the variable produced by the old value. It's empty when the variable is added.
+	Old string
+	// New is the content of the variable produced by the new value. It's empty when the variable is removed.
+	New string
+}
+
+// Diff returns the environment variables that are different between the values a and b, as if they were marshalled
+// with the prefix parts. See the method Lamenv.Diff.
+func Diff(a interface{}, b interface{}, parts []string) ([]VariableChange, error) {
+	return New().Diff(a, b, parts)
+}
+
+// Diff returns the environment variables that are different between the values a and b, sorted by their name.
+// The values are walked like Marshal would do (so following the same rules to name the variables and to render their
+// content), but without touching the environment. A nil value is producing no variable.
+//
+// The content of a variable coming from a field flagged as sensitive is masked in the result:
+//
+//	Password string `yaml:"password" lamenv:"sensitive"`
+//
+// Note: a type implementing Marshaler cannot be walked without touching the environment, so an error is returned.
+// It should implement EncoderMarshaler instead.
+func (l *Lamenv) Diff(a interface{}, b interface{}, parts []string) ([]VariableChange, error) {
+	parts = l.partsOrPrefix(parts)
+	oldVariables, oldSensitiveVariables, err := l.flatten(a, parts)
+	if err != nil {
+		return nil, err
+	}
+	newVariables, newSensitiveVariables, err := l.flatten(b, parts)
+	if err != nil {
+		return nil, err
+	}
+	var changes []VariableChange
+	for variable, oldValue := range oldVariables {
+		newValue, ok := newVariables[variable]
+		if !ok {
+			changes = append(changes, VariableChange{Variable: variable, Kind: Removed, Old: oldValue})
+		} else if newValue != oldValue {
+			changes = append(changes, VariableChange{Variable: variable, Kind: Modified, Old: oldValue, New: newValue})
+		}
+	}
+	for variable, newValue := range newVariables {
+		if _, ok := oldVariables[variable]; !ok {
+			changes = append(changes, VariableChange{Variable: variable, Kind: Added, New: newValue})
+		}
+	}
+	for i, change := range changes {
+		if oldSensitiveVariables[change.Variable] || newSensitiveVariables[change.Variable] {
+			changes[i].Old = mask(change.Old)
+			changes[i].New = mask(change.New)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Variable < changes[j].Variable
+	})
+	return changes, nil
+}
+
+// flatten returns the variables that would be set by marshalling the object, without touching the environment,
+// and the list of the ones coming from a sensitive field.
+func (l *Lamenv) flatten(object interface{}, parts []string) (map[string]string, map[string]bool, error) {
+	call := *l
+	call.setDefaults()
+	call.sink = make(map[string]string)
+	call.sensitiveVariables = make(map[string]bool)
+	if object == nil {
+		return call.sink, call.sensitiveVariables, nil
+	}
+	if err := call.encode(reflect.ValueOf(object), parts, nil); err != nil {
+		return nil, nil, err
+	}
+	return call.sink, call.sensitiveVariables, nil
+}
+
+// mask hides the content of a sensitive variable. An empty content stays empty, as there is nothing to hide.
+func mask(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	return sensitiveMask
+}
@@ -0,0 +1,127 @@
+package lamenv
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type diffDatabase struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password" lamenv:"sensitive"`
+}
+
+type diffConfig struct {
+	Timeout   time.Duration           `yaml:"timeout"`
+	Ratio     float64                 `yaml:"ratio,omitempty"`
+	Hosts     []string                `yaml:"hosts"`
+	Databases map[string]diffDatabase `yaml:"databases"`
+	Token     *string                 `yaml:"token" lamenv:"sensitive"`
+}
+
+func TestDiff(t *testing.T) {
+	token := "secret"
+	a := &diffConfig{
+		Timeout: time.Second,
+		Hosts:   []string{"a", "b"},
+		Databases: map[string]diffDatabase{
+			"main": {User: "admin", Password: "old"},
+		},
+	}
+	b := &diffConfig{
+		Timeout: time.Minute,
+		Ratio:   0.5,
+		Hosts:   []string{"a"},
+		Databases: map[string]diffDatabase{
+			"main": {User: "admin", Password: "new"},
+		},
+		Token: &token,
+	}
+	testSuites := []struct {
+		title  string
+		a      interface{}
+		b      interface{}
+		result []VariableChange
+	}{
+		{
+			title:  "same value",
+			a:      a,
+			b:      a,
+			result: nil,
+		},
+		{
+			title: "different values",
+			a:     a,
+			b:     b,
+			result: []VariableChange{
+				{Variable: "APP_DATABASES_MAIN_PASSWORD", Kind: Modified, Old: "******", New: "******"},
+				{Variable: "APP_HOSTS_1", Kind: Removed, Old: "b"},
+				{Variable: "APP_RATIO", Kind: Added, New: "0.5"},
+				{Variable: "APP_TIMEOUT", Kind: Modified, Old: "1s", New: "1m0s"},
+				{Variable: "APP_TOKEN", Kind: Added, New: "******"},
+			},
+		},
+		{
+			title: "from nothing",
+			a:     nil,
+			b:     &diffConfig{Hosts: []string{"a"}},
+			result: []VariableChange{
+				{Variable: "APP_HOSTS_0", Kind: Added, New: "a"},
+				{Variable: "APP_TIMEOUT", Kind: Added, New: "0s"},
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Diff(test.a, test.b, []string{"app"})
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestDiffWithSquashedSensitiveField(t *testing.T) {
+	type credentials struct {
+		User string `yaml:"user"`
+		Pass string `yaml:"pass"`
+	}
+	type config struct {
+		Creds credentials `yaml:",squash" lamenv:"sensitive"`
+	}
+	result, err := Diff(&config{}, &config{Creds: credentials{User: "admin", Pass: "p"}}, []string{"p"})
+	assert.NoError(t, err)
+	assert.Equal(t, []VariableChange{
+		{Variable: "P_PASS", Kind: Modified, Old: "", New: "******"},
+		{Variable: "P_USER", Kind: Modified, Old: "", New: "******"},
+	}, result)
+}
+
+func TestDiffWithSeparator(t *testing.T) {
+	result, err := New(WithSeparator("__")).Diff(&diffDatabase{User: "a"}, &diffDatabase{User: "b"}, []string{"db"})
+	assert.NoError(t, err)
+	assert.Equal(t, []VariableChange{
+		{Variable: "DB__USER", Kind: Modified, Old: "a", New: "b"},
+	}, result)
+}
+
+// legacyMarshaler is only implementing the interface Marshaler, which writes directly in the environment.
+type legacyMarshaler struct {
+	Value string
+}
+
+func (m *legacyMarshaler) MarshalEnv(parts []string) error {
+	return os.Setenv(strings.ToUpper(strings.Join(parts, "_")), m.Value)
+}
+
+func TestDiffWithMarshaler(t *testing.T) {
+	type config struct {
+		Legacy legacyMarshaler `yaml:"legacy"`
+	}
+	_, err := Diff(&config{}, &config{Legacy: legacyMarshaler{Value: "a"}}, []string{"diff"})
+	assert.EqualError(t, err, "unable to encode the variable DIFF_LEGACY without touching the environment, the type lamenv.legacyMarshaler must implement EncoderMarshaler instead of Marshaler")
+	_, exist := os.LookupEnv("DIFF_LEGACY")
+	assert.False(t, exist)
+}
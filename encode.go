@@ -3,17 +3,32 @@ package lamenv
 import (
 	"encoding"
 	"fmt"
-	"os"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
-func (l *Lamenv) encode(value reflect.Value, parts []string) error {
+// encode is looking at the type of value to know how to encode it.
+// flags is the list of options coming from the tag of the field (if any) that is encoded.
+func (l *Lamenv) encode(value reflect.Value, parts []string, flags []string) error {
+	if encoder, ok := l.encoders[value.Type()]; ok {
+		return l.encodeWithEncoder(value, parts, encoder)
+	}
 	v := value
 	// ptr will be used to try if the value is implementing the interface Marshaler.
 	// if it's the case then, the implementation of the interface has the priority.
 	ptr := value
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if registration, ok := l.interfaces[v.Type()]; ok {
+			return l.encodeRegisteredInterface(v, parts, registration)
+		}
+		return l.encode(v.Elem(), parts, flags)
+	}
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			return nil
@@ -24,7 +39,19 @@ func (l *Lamenv) encode(value reflect.Value, parts []string) error {
 		ptr.Elem().Set(v)
 	}
 
+	if encoder, ok := l.encoders[v.Type()]; ok {
+		return l.encodeWithEncoder(v, parts, encoder)
+	}
+
+	if p, ok := ptr.Interface().(EncoderMarshaler); ok {
+		return p.MarshalEnvTo(&Encoder{l: l, parts: parts})
+	}
+
 	if p, ok := ptr.Interface().(Marshaler); ok {
+		if l.sink != nil {
+			// MarshalEnv is writing directly in the environment, which must not be touched
+			return fmt.Errorf("unable to encode the variable %s without touching the environment, the type %s must implement EncoderMarshaler instead of Marshaler", l.buildEnvVariable(parts), v.Type())
+		}
 		return p.MarshalEnv(parts)
 	}
 
@@ -33,16 +60,24 @@ func (l *Lamenv) encode(value reflect.Value, parts []string) error {
 		if err != nil {
 			return err
 		}
-		return os.Setenv(buildEnvVariable(parts), string(raw))
+		return l.setEnv(l.buildEnvVariable(parts), string(raw))
+	}
+
+	if isBytes(v.Type()) {
+		return l.encodeBytes(v, parts, flags)
+	}
+
+	if _, forced := l.isJSONValue(v.Type(), flags); forced {
+		return l.encodeJSON(v, parts)
 	}
 
 	switch v.Kind() {
 	case reflect.Map:
-		if err := l.encodeMap(v, parts); err != nil {
+		if err := l.encodeMap(v, parts, flags); err != nil {
 			return err
 		}
 	case reflect.Slice:
-		if err := l.encodeSlice(v, parts); err != nil {
+		if err := l.encodeSlice(v, parts, flags); err != nil {
 			return err
 		}
 	case reflect.Struct:
@@ -50,42 +85,140 @@ func (l *Lamenv) encode(value reflect.Value, parts []string) error {
 			return err
 		}
 	default:
-		return l.encodeNative(v, buildEnvVariable(parts))
+		return l.encodeNative(v, l.buildEnvVariable(parts))
 	}
 	return nil
 }
 
+// encodeWithEncoder uses an encoder registered by the user to encode the value.
+func (l *Lamenv) encodeWithEncoder(value reflect.Value, parts []string, encoder EncodeFunc) error {
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil
+	}
+	variable := l.buildEnvVariable(parts)
+	result, err := encoder(value.Interface())
+	if err != nil {
+		return fmt.Errorf("unable to encode the variable %s: %w", variable, err)
+	}
+	return l.setEnv(variable, result)
+}
+
+// encodeRegisteredInterface encodes the concrete value and sets the discriminator with the name of its type.
+func (l *Lamenv) encodeRegisteredInterface(value reflect.Value, parts []string, registration *interfaceRegistration) error {
+	concreteType := value.Elem().Type()
+	for name, t := range registration.types {
+		if t != concreteType {
+			continue
+		}
+		if err := l.encode(value.Elem(), parts, nil); err != nil {
+			return err
+		}
+		return l.setEnv(l.buildEnvVariable(append(parts, registration.discriminator)), name)
+	}
+	return fmt.Errorf("unable to encode the interface %s, the type %s is not registered", value.Type(), concreteType)
+}
+
 func (l *Lamenv) encodeNative(value reflect.Value, input string) error {
-	return os.Setenv(input, nativeToString(value))
+	return l.setEnv(input, nativeToString(value))
+}
+
+// encodeBytes encodes a []byte or a [N]byte into a single environment variable, using the encoding chosen.
+func (l *Lamenv) encodeBytes(value reflect.Value, parts []string, flags []string) error {
+	if value.Kind() == reflect.Slice && value.IsNil() {
+		return nil
+	}
+	b := make([]byte, value.Len())
+	reflect.Copy(reflect.ValueOf(b), value)
+	return l.setEnv(l.buildEnvVariable(parts), l.bytesEncodingOf(flags).encode(b))
 }
 
-func (l *Lamenv) encodeSlice(value reflect.Value, parts []string) error {
+func (l *Lamenv) encodeSlice(value reflect.Value, parts []string, flags []string) error {
 	if value.IsNil() {
 		return nil
 	}
+	if l.isDelimitedList(value.Type(), flags) {
+		return l.encodeDelimitedList(value, parts)
+	}
 	for i := 0; i < value.Len(); i++ {
-		if err := l.encode(value.Index(i), append(parts, strconv.Itoa(i))); err != nil {
+		if err := l.encode(value.Index(i), append(parts, strconv.Itoa(i)), nil); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (l *Lamenv) encodeMap(value reflect.Value, parts []string) error {
+func (l *Lamenv) encodeDelimitedList(value reflect.Value, parts []string) error {
+	items := make([]string, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		item, err := l.encodeScalar(value.Index(i))
+		if err != nil {
+			return err
+		}
+		items[i] = escape(item, l.listSeparator)
+	}
+	return l.setEnv(l.buildEnvVariable(parts), strings.Join(items, l.listSeparator))
+}
+
+func (l *Lamenv) encodeMap(value reflect.Value, parts []string, flags []string) error {
 	if value.IsNil() {
 		return nil
 	}
+	if l.isInlineMap(value.Type(), flags) {
+		return l.encodeInlineMap(value, parts)
+	}
+	// when the value is itself a map, the key must be followed by an empty part to stay determinist when unmarshalling it.
+	mapLevel := newRing(value.Type().Elem(), l).isMapLevel(value.Type().Elem())
 	iter := value.MapRange()
 	for iter.Next() {
-		k := iter.Key()
-		v := iter.Value()
-		if err := l.encode(v, append(parts, nativeToString(k))); err != nil {
+		k, err := encodeMapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		valueParts := append(parts, k)
+		if mapLevel {
+			valueParts = append(valueParts, "")
+		}
+		if err := l.encode(iter.Value(), valueParts, nil); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (l *Lamenv) encodeInlineMap(value reflect.Value, parts []string) error {
+	pairs := make([]string, 0, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		k, err := encodeMapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		v, err := l.encodeScalar(iter.Value())
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, escape(k, l.mapPairSeparator, l.mapKeyValueSeparator)+l.mapKeyValueSeparator+escape(v, l.mapPairSeparator, l.mapKeyValueSeparator))
+	}
+	// the order of a map is random, so the pairs are sorted to always produce the same variable.
+	sort.Strings(pairs)
+	return l.setEnv(l.buildEnvVariable(parts), strings.Join(pairs, l.mapPairSeparator))
+}
+
+// encodeMapKey returns the string representation of the key of a map.
+// It is the symmetric of the method decodeMapKey.
+func encodeMapKey(key reflect.Value) (string, error) {
+	ptr := reflect.New(key.Type())
+	ptr.Elem().Set(key)
+	if p, ok := ptr.Interface().(encoding.TextMarshaler); ok {
+		raw, err := p.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(raw), nil
+	}
+	return nativeToString(key), nil
+}
+
 func (l *Lamenv) encodeStruct(value reflect.Value, parts []string) error {
 	for i := 0; i < value.NumField(); i++ {
 		field := value.Field(i)
@@ -95,6 +228,8 @@ func (l *Lamenv) encodeStruct(value reflect.Value, parts []string) error {
 			continue
 		}
 		var fieldName string
+		// encoder is the one used for the field, since a sensitive field requires a different one
+		encoder := l
 		tags, ok := l.lookupTag(fieldType.Tag)
 		if ok {
 			fieldName = tags[0]
@@ -102,20 +237,27 @@ func (l *Lamenv) encodeStruct(value reflect.Value, parts []string) error {
 			if fieldName == "-" {
 				continue
 			}
-			if containStr(tags, squash) || containStr(tags, inline) {
-				if err := l.encode(field, parts); err != nil {
-					return err
-				}
-				continue
-			}
-			if containStr(tags, omitempty) && isZero(field) {
-				continue
-			}
 		} else {
 			fieldName = fieldType.Name
 		}
+		flags := lookupFlags(fieldType.Tag)
+		if containStr(flags, sensitive) && !l.sensitive {
+			// every variable produced by the field is sensitive, whatever its depth
+			sensitiveEncoder := *l
+			sensitiveEncoder.sensitive = true
+			encoder = &sensitiveEncoder
+		}
+		if containStr(tags, squash) || containStr(tags, inline) {
+			if err := encoder.encode(field, parts, flags); err != nil {
+				return err
+			}
+			continue
+		}
+		if containStr(tags, omitempty) && isZero(field) {
+			continue
+		}
 
-		if err := l.encode(value.Field(i), append(parts, fieldName)); err != nil {
+		if err := encoder.encode(value.Field(i), append(parts, fieldName), flags); err != nil {
 			return err
 		}
 	}
@@ -143,6 +285,9 @@ func isZero(v reflect.Value) bool {
 	case reflect.Float32,
 		reflect.Float64:
 		return v.Float() == 0
+	case reflect.Complex64,
+		reflect.Complex128:
+		return v.Complex() == 0
 	case reflect.Uint,
 		reflect.Uint8,
 		reflect.Uint16,
@@ -154,6 +299,10 @@ func isZero(v reflect.Value) bool {
 		return !v.Bool()
 	case reflect.Struct:
 		vt := v.Type()
+		if reflect.PtrTo(vt).Implements(textMarshalerType) {
+			// it is usually a struct without any public field like time.Time
+			return v.IsZero()
+		}
 		for i := 0; i < v.NumField(); i++ {
 			if len(vt.Field(i).PkgPath) > 0 {
 				continue // Private field
@@ -187,10 +336,17 @@ func nativeToString(value reflect.Value) string {
 		reflect.Uint16,
 		reflect.Uint32,
 		reflect.Uint64:
+		if value.Type() == byteSizeType {
+			return ByteSize(value.Uint()).String()
+		}
 		return fmt.Sprintf("%d", value.Uint())
 	case reflect.Float32,
 		reflect.Float64:
-		return fmt.Sprintf("%f", value.Float())
+		// the shortest representation that gives back the exact same value once parsed
+		return strconv.FormatFloat(value.Float(), 'g', -1, value.Type().Bits())
+	case reflect.Complex64,
+		reflect.Complex128:
+		return strconv.FormatComplex(value.Complex(), 'g', -1, value.Type().Bits())
 	}
 	return ""
 }
@@ -0,0 +1,113 @@
+package lamenv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSource is a Source reading the variables from a dotenv file, where each line looks like:
+//
+//	# a comment
+//	export MY_PREFIX_HOST=localhost
+//	MY_PREFIX_NAME="my app"
+//
+// The file is read once when the source is created, then each time Refresh is called and the file has been modified.
+type FileSource struct {
+	path      string
+	mutex     sync.RWMutex
+	variables map[string]string
+	modTime   time.Time
+	size      int64
+}
+
+// NewFileSource reads the dotenv file located at path.
+func NewFileSource(path string) (*FileSource, error) {
+	f := &FileSource{path: path}
+	if _, err := f.Refresh(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Lookup returns the value of the variable and if it exists.
+func (f *FileSource) Lookup(variable string) (string, bool) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	value, ok := f.variables[variable]
+	return value, ok
+}
+
+// Variables returns the name of every variable defined in the file.
+func (f *FileSource) Variables() []string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	variables := make([]string, 0, len(f.variables))
+	for variable := range f.variables {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// Refresh reads the file again if it has been modified since the last time it was read,
+// which is known by looking at its modification time and its size.
+// It returns true if the file has been read.
+func (f *FileSource) Refresh() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.mutex.RLock()
+	modified := f.variables == nil || !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+	f.mutex.RUnlock()
+	if !modified {
+		return false, nil
+	}
+	variables, err := readDotEnv(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.mutex.Lock()
+	f.variables = variables
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.mutex.Unlock()
+	return true, nil
+}
+
+func readDotEnv(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	variables := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		keyValue := strings.SplitN(line, "=", 2)
+		if len(keyValue) != 2 || len(strings.TrimSpace(keyValue[0])) == 0 {
+			return nil, fmt.Errorf("unable to read the file %s: the line %d is not a variable", path, lineNumber)
+		}
+		value := strings.TrimSpace(keyValue[1])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		variables[strings.TrimSpace(keyValue[0])] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
@@ -0,0 +1,60 @@
+package lamenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDotEnv(t *testing.T, path string, content string, modTime time.Time) {
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	// the modification time is set explicitly, since two writes can happen within the precision of the filesystem
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	now := time.Now()
+	writeDotEnv(t, path, `
+# a comment
+export APP_HOST=localhost
+APP_NAME="my app"
+APP_MOTTO='get it, use it, forget it'
+APP_EMPTY=
+`, now)
+	source, err := NewFileSource(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"APP_EMPTY", "APP_HOST", "APP_MOTTO", "APP_NAME"}, source.Variables())
+	value, ok := source.Lookup("APP_NAME")
+	assert.True(t, ok)
+	assert.Equal(t, "my app", value)
+	value, ok = source.Lookup("APP_MOTTO")
+	assert.True(t, ok)
+	assert.Equal(t, "get it, use it, forget it", value)
+	_, ok = source.Lookup("APP_PORT")
+	assert.False(t, ok)
+
+	modified, err := source.Refresh()
+	assert.NoError(t, err)
+	assert.False(t, modified)
+
+	writeDotEnv(t, path, "APP_HOST=example.com\n", now.Add(time.Second))
+	modified, err = source.Refresh()
+	assert.NoError(t, err)
+	assert.True(t, modified)
+	assert.Equal(t, []string{"APP_HOST"}, source.Variables())
+}
+
+func TestFileSourceError(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewFileSource(filepath.Join(dir, "missing.env"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, ".env")
+	writeDotEnv(t, path, "APP_HOST=localhost\nnot a variable\n", time.Now())
+	_, err = NewFileSource(path)
+	assert.EqualError(t, err, "unable to read the file "+path+": the line 2 is not a variable")
+}
@@ -0,0 +1,91 @@
+package lamenv
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// The DecoderUnmarshaler interface may be implemented by types to customize their behavior when being unmarshaled
+// from a series of environment variable. Unlike Unmarshaler, the type receives a Decoder, so it reads the variables
+// the same way the rest of the object is decoded. It has the priority over Unmarshaler.
+type DecoderUnmarshaler interface {
+	UnmarshalEnvFrom(d *Decoder) error
+}
+
+// The EncoderMarshaler interface may be implemented by types to customize their behavior when being marshaled into a
+// series of environment variable. Unlike Marshaler, the type receives an Encoder, so it writes the variables the same
+// way the rest of the object is encoded. It has the priority over Marshaler.
+type EncoderMarshaler interface {
+	MarshalEnvTo(e *Encoder) error
+}
+
+// Decoder is the handle given to a DecoderUnmarshaler. Every method is taking parts relative to the prefix of the type
+// being decoded. With no parts, it's the variable named by the prefix itself.
+type Decoder struct {
+	l     *Lamenv
+	parts []string
+}
+
+// Parts returns the prefix of the type being decoded.
+func (d *Decoder) Parts() []string {
+	return append([]string(nil), d.parts...)
+}
+
+// Lookup returns the value of the variable and if it exists.
+// The variable is not considered as used until MarkUsed is called.
+func (d *Decoder) Lookup(parts ...string) (string, bool) {
+	_, value, exist := d.l.lookupEnv(d.join(parts))
+	return value, exist
+}
+
+// Has returns true if the variable exists, or if one variable is starting with it.
+func (d *Decoder) Has(parts ...string) bool {
+	return d.l.containsVariable(d.join(parts))
+}
+
+// MarkUsed declares the variable as used, so it is not considered anymore when guessing the keys of a map.
+func (d *Decoder) MarkUsed(parts ...string) {
+	d.l.markUsed(d.l.buildEnvVariable(d.join(parts)))
+}
+
+// DecodeInto decodes the variables into sub, like it would be done for a field of a struct. sub must be a pointer.
+func (d *Decoder) DecodeInto(sub interface{}, parts ...string) error {
+	v := reflect.ValueOf(sub)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("unable to decode into a %T, a non-nil pointer is required", sub)
+	}
+	return d.l.decode(v, d.join(parts), nil)
+}
+
+func (d *Decoder) join(parts []string) []string {
+	return append(append([]string(nil), d.parts...), parts...)
+}
+
+// Encoder is the handle given to an EncoderMarshaler. Every method is taking parts relative to the prefix of the type
+// being encoded. With no parts, it's the variable named by the prefix itself.
+type Encoder struct {
+	l     *Lamenv
+	parts []string
+}
+
+// Parts returns the prefix of the type being encoded.
+func (e *Encoder) Parts() []string {
+	return append([]string(nil), e.parts...)
+}
+
+// Set writes the value into the variable.
+func (e *Encoder) Set(value string, parts ...string) error {
+	return e.l.setEnv(e.l.buildEnvVariable(e.join(parts)), value)
+}
+
+// EncodeInto encodes sub, like it would be done for a field of a struct.
+func (e *Encoder) EncodeInto(sub interface{}, parts ...string) error {
+	if sub == nil {
+		return nil
+	}
+	return e.l.encode(reflect.ValueOf(sub), e.join(parts), nil)
+}
+
+func (e *Encoder) join(parts []string) []string {
+	return append(append([]string(nil), e.parts...), parts...)
+}
@@ -0,0 +1,100 @@
+package lamenv
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// database is decoding its address from two variables, and the rest as any other field.
+type database struct {
+	address string
+	timeout time.Duration
+}
+
+func (d *database) UnmarshalEnvFrom(decoder *Decoder) error {
+	host, ok := decoder.Lookup("host")
+	if !ok {
+		return nil
+	}
+	decoder.MarkUsed("host")
+	port := "5432"
+	if p, ok := decoder.Lookup("port"); ok {
+		decoder.MarkUsed("port")
+		port = p
+	}
+	d.address = host + ":" + port
+	return decoder.DecodeInto(&d.timeout, "timeout")
+}
+
+func (d *database) MarshalEnvTo(encoder *Encoder) error {
+	if err := encoder.Set("db.local", "host"); err != nil {
+		return err
+	}
+	return encoder.EncodeInto(d.timeout, "timeout")
+}
+
+func (d *database) EnvShape() []string {
+	return []string{"HOST", "PORT", "TIMEOUT"}
+}
+
+func TestDecoderUnmarshaler(t *testing.T) {
+	env := map[string]string{
+		"DATABASES_MAIN_HOST":    "main.local",
+		"DATABASES_MAIN_TIMEOUT": "5s",
+		"DATABASES_LOGS_HOST":    "logs.local",
+		"DATABASES_LOGS_PORT":    "5433",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	c := &struct {
+		Databases map[string]database
+	}{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, map[string]database{
+		"main": {address: "main.local:5432", timeout: 5 * time.Second},
+		"logs": {address: "logs.local:5433"},
+	}, c.Databases)
+}
+
+func TestDecoder(t *testing.T) {
+	_ = os.Setenv("APP_DB_HOST", "localhost")
+	defer os.Unsetenv("APP_DB_HOST")
+	l := New().newCall()
+	d := &Decoder{l: l, parts: []string{"app", "db"}}
+	assert.Equal(t, []string{"app", "db"}, d.Parts())
+	assert.True(t, d.Has())
+	assert.True(t, d.Has("host"))
+	assert.False(t, d.Has("port"))
+	value, ok := d.Lookup("host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost", value)
+	assert.True(t, l.env["APP_DB_HOST"])
+	d.MarkUsed("host")
+	assert.False(t, l.env["APP_DB_HOST"])
+	var i int
+	assert.Error(t, d.DecodeInto(i, "port"))
+}
+
+func TestEncoderMarshaler(t *testing.T) {
+	defer func() {
+		_ = os.Unsetenv("DB_HOST")
+		_ = os.Unsetenv("DB_TIMEOUT")
+	}()
+	c := &struct {
+		DB *database
+	}{
+		DB: &database{timeout: time.Minute},
+	}
+	assert.NoError(t, Marshal(c, nil))
+	assert.Equal(t, "db.local", os.Getenv("DB_HOST"))
+	assert.Equal(t, "1m0s", os.Getenv("DB_TIMEOUT"))
+}
@@ -0,0 +1,62 @@
+package lamenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// DecodeHookFunc is a function called with the value of an environment variable (from) and the type it will be decoded
+// into (to), before parsing it.
+//
+// When the hook returns a string, it replaces the value and it is passed to the next hook of the chain.
+// When it returns anything else, the chain stops and the result is used as the decoded value.
+// It must then be assignable or convertible to the type to.
+type DecodeHookFunc func(from string, to reflect.Type) (interface{}, error)
+
+// TrimSpaceHook returns a DecodeHookFunc that removes the leading and trailing white spaces.
+func TrimSpaceHook() DecodeHookFunc {
+	return func(from string, _ reflect.Type) (interface{}, error) {
+		return strings.TrimSpace(from), nil
+	}
+}
+
+// StripQuotesHook returns a DecodeHookFunc that removes the double or single quotes surrounding the value.
+func StripQuotesHook() DecodeHookFunc {
+	return func(from string, _ reflect.Type) (interface{}, error) {
+		if len(from) >= 2 && (from[0] == '"' || from[0] == '\'') && from[len(from)-1] == from[0] {
+			return from[1 : len(from)-1], nil
+		}
+		return from, nil
+	}
+}
+
+// FoldCaseHook returns a DecodeHookFunc that replaces the value by the one of the list provided that is equal
+// under Unicode case-folding. It's useful to accept any casing for the value of an enum.
+// When no value is matching, the value is kept as it is.
+func FoldCaseHook(values ...string) DecodeHookFunc {
+	return func(from string, _ reflect.Type) (interface{}, error) {
+		for _, value := range values {
+			if strings.EqualFold(from, value) {
+				return value, nil
+			}
+		}
+		return from, nil
+	}
+}
+
+// ExpandHomeHook returns a DecodeHookFunc that replaces the leading ~ by the home directory of the current user,
+// when the value is decoded into a string.
+func ExpandHomeHook() DecodeHookFunc {
+	return func(from string, to reflect.Type) (interface{}, error) {
+		if to.Kind() != reflect.String || (from != "~" && !strings.HasPrefix(from, "~/")) {
+			return from, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		return filepath.Join(home, from[1:]), nil
+	}
+}
@@ -0,0 +1,69 @@
+package lamenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeHook(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+	type config struct {
+		Level   string
+		File    string
+		Quoted  string
+		Enabled bool
+		Count   int
+	}
+	env := map[string]string{
+		"LEVEL":   "  WaRn ",
+		"FILE":    "~/config.yaml",
+		"QUOTED":  `"my value"`,
+		"ENABLED": " 'yes' ",
+		"COUNT":   "  42",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	yesHook := func(from string, to reflect.Type) (interface{}, error) {
+		if to.Kind() == reflect.Bool && strings.EqualFold(from, "yes") {
+			return true, nil
+		}
+		return from, nil
+	}
+	c := &config{}
+	err = New().
+		AddDecodeHook(TrimSpaceHook(), StripQuotesHook()).
+		AddDecodeHook(FoldCaseHook("debug", "info", "warn"), ExpandHomeHook(), yesHook).
+		Unmarshal(c, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &config{
+		Level:   "warn",
+		File:    filepath.Join(home, "config.yaml"),
+		Quoted:  "my value",
+		Enabled: true,
+		Count:   42,
+	}, c)
+}
+
+func TestDecodeHookWithWrongType(t *testing.T) {
+	_ = os.Setenv("COUNT", "42")
+	defer os.Unsetenv("COUNT")
+	c := &struct {
+		Count int
+	}{}
+	err := New().AddDecodeHook(func(from string, to reflect.Type) (interface{}, error) {
+		return []string{from}, nil
+	}).Unmarshal(c, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package lamenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonValue is the flag used in a tag to provide a struct, a slice or a map as a single JSON document.
+const jsonValue = "json"
+
+// isJSONValue returns true when the type can be decoded from a single JSON document.
+// When forced is false, the document is only decoded if it looks like a JSON object or array.
+func (l *Lamenv) isJSONValue(t reflect.Type, flags []string) (ok bool, forced bool) {
+	switch t.Kind() {
+	case reflect.Struct,
+		reflect.Slice,
+		reflect.Array,
+		reflect.Map:
+		forced = containStr(flags, jsonValue)
+		return forced || l.jsonValue, forced
+	}
+	return false, false
+}
+
+// decodeJSON decodes the variable <PREFIX> as a JSON document using encoding/json.
+// The content of v is not reset, so the JSON document is merged with what is already set.
+func (l *Lamenv) decodeJSON(v reflect.Value, parts []string, forced bool) error {
+	variable, input, exist := l.lookupEnv(parts)
+	if !exist {
+		return nil
+	}
+	if !forced && !looksLikeJSON(input) {
+		return nil
+	}
+	// remove the variable to avoid reusing it later
+	l.markUsed(variable)
+	if err := json.Unmarshal([]byte(input), v.Addr().Interface()); err != nil {
+		return fmt.Errorf("unable to decode the variable %s as a JSON document: %w", variable, err)
+	}
+	return nil
+}
+
+func (l *Lamenv) encodeJSON(value reflect.Value, parts []string) error {
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.IsNil() {
+		return nil
+	}
+	variable := l.buildEnvVariable(parts)
+	raw, err := json.Marshal(value.Interface())
+	if err != nil {
+		return fmt.Errorf("unable to encode the variable %s as a JSON document: %w", variable, err)
+	}
+	return l.setEnv(variable, string(raw))
+}
+
+func looksLikeJSON(input string) bool {
+	trimmedInput := strings.TrimSpace(input)
+	return strings.HasPrefix(trimmedInput, "{") || strings.HasPrefix(trimmedInput, "[")
+}
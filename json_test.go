@@ -0,0 +1,80 @@
+package lamenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONValue(t *testing.T) {
+	type rule struct {
+		Name  string `json:"name" yaml:"name"`
+		Value int    `json:"value" yaml:"value"`
+	}
+	type config struct {
+		Rules  []rule         `yaml:"rules" lamenv:"json"`
+		Limits map[string]int `yaml:"limits" lamenv:"json"`
+		Server *struct {
+			Host string `json:"host" yaml:"host"`
+			Port int    `json:"port" yaml:"port"`
+		} `yaml:"server" lamenv:"json"`
+		NotJSON []string `yaml:"not_json"`
+	}
+	env := map[string]string{
+		"RULES":         `[{"name": "a", "value": 1}, {"name": "b", "value": 2}]`,
+		"RULES_0_NAME":  "z",
+		"RULES_1_VALUE": "3",
+		"LIMITS":        `{"cpu": 2, "memory": 512}`,
+		"LIMITS_CPU":    "4",
+		"SERVER":        `{"host": "localhost", "port": 80}`,
+		"SERVER_PORT":   "8080",
+		"NOT_JSON":      `["a"]`,
+		"NOT_JSON_0":    "b",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, []rule{{Name: "z", Value: 1}, {Name: "b", Value: 3}}, c.Rules)
+	assert.Equal(t, map[string]int{"cpu": 4, "memory": 512}, c.Limits)
+	assert.Equal(t, "localhost", c.Server.Host)
+	assert.Equal(t, 8080, c.Server.Port)
+	assert.Equal(t, []string{"b"}, c.NotJSON)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	c.NotJSON = nil
+	assert.NoError(t, Marshal(c, nil))
+	assert.Equal(t, `[{"name":"z","value":1},{"name":"b","value":3}]`, os.Getenv("RULES"))
+	assert.Equal(t, `{"cpu":4,"memory":512}`, os.Getenv("LIMITS"))
+	assert.Equal(t, `{"host":"localhost","port":8080}`, os.Getenv("SERVER"))
+	_, exist := os.LookupEnv("RULES_0_NAME")
+	assert.False(t, exist)
+	for _, k := range []string{"RULES", "LIMITS", "SERVER"} {
+		_ = os.Unsetenv(k)
+	}
+
+	_ = os.Setenv("RULES", "not a json")
+	assert.Error(t, Unmarshal(&config{}, nil))
+	_ = os.Unsetenv("RULES")
+}
+
+func TestLamenv_JSONValue(t *testing.T) {
+	_ = os.Setenv("PREFIX_HOSTS", `["a", "b"]`)
+	_ = os.Setenv("PREFIX_ORIGINS", "a,b")
+	defer func() {
+		_ = os.Unsetenv("PREFIX_HOSTS")
+		_ = os.Unsetenv("PREFIX_ORIGINS")
+	}()
+	c := &struct {
+		Hosts   []string
+		Origins []string `yaml:"origins" lamenv:"list"`
+	}{}
+	assert.NoError(t, New().JSONValue(true).Unmarshal(c, []string{"PREFIX"}))
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	// the value doesn't look like a JSON document, so the delimited list is used
+	assert.Equal(t, []string{"a", "b"}, c.Origins)
+}
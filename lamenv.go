@@ -8,9 +8,14 @@
 package lamenv
 
 import (
+	"fmt"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 var defaultTagSupported = []string{
@@ -21,12 +26,25 @@ var defaultTagSupported = []string{
 // behavior when being unmarshaled from a series of environment varialb.
 //
 // parts is the list of prefix that would composed the final environment variables.
-// Note: for the moment, customize the way to decode a type may not work when using a map.
-// The main reason is because the system is then not able to determinate which key it has to use when unmarshalling a map.
+// Note: when the type is the value of a map, it should also implement the interface EnvShaper.
+// Otherwise, the system is looking at the fields of the type to determinate which key it has to use when unmarshalling
+// the map, and that may not be the variables actually read by UnmarshalEnv.
 type Unmarshaler interface {
 	UnmarshalEnv(parts []string) error
 }
 
+// The EnvShaper interface may be implemented by types to declare which environment variables they are reading,
+// instead of letting lamenv guess it from the fields of the type.
+// It is used to find the key of a map when the type is the value of the map, which is mainly useful for a type
+// implementing Unmarshaler or DecoderUnmarshaler.
+//
+// EnvShape returns the suffixes (relative to the prefix of the type) of the variables read, like "HOST" or "TLS_CERT".
+// An empty suffix means the variable named by the prefix itself.
+// The method is called on the zero value of the type.
+type EnvShaper interface {
+	EnvShape() []string
+}
+
 // The Marshaler interface may be implemented by types to customize their
 // behavior when being marshaled into a series of environment variable document.
 //
@@ -51,6 +69,12 @@ type Marshaler interface {
 // uppercased as the default key. Custom keys can be defined via the
 // "json", "yaml" and "mapstructure" name in the field tag.
 // If multiple tag name are defined, "json" is considered at first, then "yaml" and finally "mapstructure".
+// The flags specific to lamenv are set in the tag "lamenv", see Marshal for the list of flags.
+//
+// An empty interface is decoded without any schema: every environment variable starting with the prefix is decoded
+// into a tree made of map[string]interface{} and []interface{}. Segments that are numbers are considered as indexes of
+// a slice, the other ones as keys of a map. Use the method InferScalarTypes to get a bool, an int or a float64 instead
+// of a string when it's possible.
 //
 // Note: When using a map, it's possible for the Unmarshal method to fail because it's finding multiple way to unmarshal
 // the same environment variable for different field in the struct (that could be at different depth).
@@ -99,6 +123,29 @@ func Unmarshal(object interface{}, parts []string) error {
 //
 // In addition, if the key is "-", the field is ignored.
 //
+// The flags specific to lamenv are set in their own tag, since a library like gopkg.in/yaml.v3 refuses the flags it
+// doesn't know. They are used by Unmarshal and Diff as well:
+//
+//     `(...) lamenv:"<flag1>[,<flag2>]" (...)`
+//
+// The following flags are currently supported:
+//
+//     merge, replace         The way to decode a slice or a map that is already populated.
+//     or append              See Lamenv.Merge.
+//
+//     base64, base64url,     The encoding of a []byte or a [N]byte. See Lamenv.BytesEncoding.
+//     hex or raw
+//
+//     list                   The slice of scalar is a single delimited variable. See Lamenv.DelimitedList.
+//
+//     kv                     The map of scalar is a single variable containing the pairs of key/value.
+//                            See Lamenv.InlineMap.
+//
+//     json                   The struct, the slice or the map is a single variable containing a JSON document.
+//                            See Lamenv.JSONValue.
+//
+//     sensitive              The content of the field is masked in the result of Diff.
+//
 // parts is the list of prefix of the future environment variable. It can be empty.
 func Marshal(object interface{}, parts []string) error {
 	return New().Marshal(object, parts)
@@ -111,44 +158,343 @@ type Lamenv struct {
 	tagSupports []string
 	// env is the map that is representing the list of the environment variable visited
 	// The key is the name of the variable.
-	// The value is not important, since once the variable would be used, then the key will be removed
+	// The value is not important, since once the variable would be used, then the key will be removed.
+	// It is only set on the copy made for each call to Unmarshal, never on the Lamenv itself.
 	// It will be useful when a map is involved in order to not parse every possible variable
 	// but only the one that are still not used.
 	env map[string]bool
+	// source is where the environment variables are read from.
+	source Source
+	// snapshot is the copy of the variables available in the source, taken by New or by Reload.
+	snapshot *atomic.Pointer[MapSource]
+	// snapshotPerCall is true when a new snapshot is taken at each call to Unmarshal.
+	snapshotPerCall bool
+	// prefix is the list of prefix used by Load.
+	prefix []string
+	// strict is true when unmarshalling must fail if a variable starting with the prefix has not been used.
+	strict bool
+	// separator is the separator used between the parts of an environment variable.
+	separator string
+	// logger is used to trace the variables decoded. It can be nil.
+	logger Logger
+	// sink is where the variables are written when marshalling. When nil, they are written in the environment of the process.
+	sink map[string]string
+	// sensitiveVariables is the list of variables written in the sink that are coming from a sensitive field.
+	sensitiveVariables map[string]bool
+	// sensitive is true while encoding a field flagged as sensitive.
+	sensitive bool
+	// mapKeyCase is the policy used to turn the key found in an environment variable into the key of a map.
+	mapKeyCase KeyCase
+	// inferScalarTypes is used when decoding an interface{}.
+	// When true, a value will be converted to a bool, an int or a float64 if it's possible.
+	inferScalarTypes bool
+	// interfaces is the list of interface registered with the list of concrete type that can be used to decode them.
+	interfaces map[reflect.Type]*interfaceRegistration
+	// decoders is the list of functions registered by the user to decode a specific type.
+	decoders map[reflect.Type]DecodeFunc
+	// encoders is the list of functions registered by the user to encode a specific type.
+	encoders map[reflect.Type]EncodeFunc
+	// decodeHooks is the chain of functions applied on the value of an environment variable before parsing it.
+	decodeHooks []DecodeHookFunc
+	// timeLayout is the layout used to decode and encode a time.Time
+	timeLayout string
+	// bytesEncoding is the encoding used by default for a []byte or a [N]byte.
+	bytesEncoding BytesEncoding
+	// delimitedList is true when every slice of scalar can be provided as a single delimited variable.
+	delimitedList bool
+	// listSeparator is the separator used between the items of a delimited variable.
+	listSeparator string
+	// inlineMap is true when every map of scalar can be provided as a single variable containing the pairs of key/value.
+	inlineMap bool
+	// mapPairSeparator is the separator used between the pairs of key/value of an inline map.
+	mapPairSeparator string
+	// mapKeyValueSeparator is the separator used between the key and the value of an inline map.
+	mapKeyValueSeparator string
+	// jsonValue is true when every struct, slice or map can be provided as a single JSON document.
+	jsonValue bool
+	// sliceGapPolicy is the way to treat the gaps between the indexes of a slice.
+	sliceGapPolicy SliceGapPolicy
+	// sliceLength is true when the variable <PREFIX>_LEN can be used to declare the length of a slice.
+	sliceLength bool
+	// maxSliceLength is the maximum length of a slice that can be declared by <PREFIX>_LEN or reached by an index
+	// when the gaps are filled.
+	maxSliceLength int
+	// mergeStrategy is the way to decode a slice or a map that is already populated.
+	mergeStrategy MergeStrategy
 }
 
-// New is the method to use to initialize the struct Lamenv.
-// The struct can then be fine tuned using the appropriate exported method.
-func New() *Lamenv {
-	env := make(map[string]bool)
-	for _, e := range os.Environ() {
-		envSplit := strings.SplitN(e, "=", 2)
-		if len(envSplit) != 2 {
-			continue
+// MergeStrategy is the way to decode a slice or a map that is already populated, like when the environment variables
+// are used to override a configuration loaded from a file.
+type MergeStrategy int
+
+const (
+	// MergeByIndex is the default strategy. The items of a slice are overridden by index and the new ones are appended.
+	// The keys of a map are added to the ones already present.
+	MergeByIndex MergeStrategy = iota
+	// Replace drops the current content of the slice or the map as soon as one environment variable is defining it.
+	Replace
+	// Append adds the items decoded at the end of the slice. For a map, it is the same as MergeByIndex.
+	Append
+)
+
+const (
+	mergeByIndex = "merge"
+	replace      = "replace"
+	appendItems  = "append"
+)
+
+// SliceGapPolicy is the way to treat the gaps between the indexes of a slice, like when only the variables
+// <PREFIX>_0 and <PREFIX>_2 are defined.
+type SliceGapPolicy int
+
+const (
+	// StopAtGap is the default policy. The slice is decoded until the first missing index, the next ones are ignored.
+	StopAtGap SliceGapPolicy = iota
+	// CompactGaps decodes every index found, and puts them next to each other in the slice by keeping their order.
+	CompactGaps
+	// FillGaps decodes every index found at its position in the slice. The missing indexes are left to the zero value
+	// (or to the value already present in the slice).
+	FillGaps
+	// ErrorOnGap returns an error when an index is missing.
+	ErrorOnGap
+)
+
+// sliceLengthSuffix is the suffix of the variable declaring the length of a slice.
+const sliceLengthSuffix = "LEN"
+
+// defaultMaxSliceLength is the maximum length of a slice by default. See the method MaxSliceLength.
+const defaultMaxSliceLength = 10000
+
+// DecodeFunc is a function that decodes the value of an environment variable into a specific type.
+// The value returned must be assignable to the type the function is registered for.
+type DecodeFunc func(input string) (interface{}, error)
+
+// EncodeFunc is a function that encodes a value of a specific type into the value of an environment variable.
+type EncodeFunc func(value interface{}) (string, error)
+
+// interfaceRegistration holds what is needed to decode an interface into the appropriate concrete type.
+type interfaceRegistration struct {
+	// discriminator is the name of the field containing the name of the concrete type to use.
+	discriminator string
+	// types is the list of concrete types with their name as a key.
+	types map[string]reflect.Type
+}
+
+// KeyCase is the policy applied when decoding a map to transform the key found in the environment variable
+// into the key that will be set in the map.
+// Since an environment variable is always uppercased by lamenv, the key received is uppercased too.
+type KeyCase func(key string) string
+
+var (
+	// LowerCase is the default KeyCase. Every key of a map will be lowercased.
+	LowerCase KeyCase = strings.ToLower
+	// UpperCase keeps the key uppercased, as it is in the environment variable.
+	UpperCase KeyCase = strings.ToUpper
+)
+
+// PreserveCase returns a KeyCase that restores the original casing of the keys provided.
+// As the environment variables are uppercased, it's not possible to guess what was the original casing of a key.
+// That's why the canonical keys must be declared. When a key is not matching any of them, it is lowercased.
+func PreserveCase(keys ...string) KeyCase {
+	canonicalKeys := make(map[string]string, len(keys))
+	for _, key := range keys {
+		canonicalKeys[strings.ToUpper(key)] = key
+	}
+	return func(key string) string {
+		if canonicalKey, ok := canonicalKeys[strings.ToUpper(key)]; ok {
+			return canonicalKey
 		}
-		env[envSplit[0]] = true
+		return strings.ToLower(key)
+	}
+}
+
+// Logger is used to trace how the environment variables are decoded. *log.Logger is implementing it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// defaultSeparator is the separator used by default between the parts of an environment variable.
+const defaultSeparator = "_"
+
+// New is the method to use to initialize the struct Lamenv.
+// The struct can be configured using the options passed as parameter, or fine tuned using the appropriate exported method.
+// Using only the options is a way to be sure the configuration won't change once the struct is shared.
+func New(opts ...Option) *Lamenv {
+	l := &Lamenv{
+		tagSupports:          defaultTagSupports(),
+		source:               OSSource,
+		mapKeyCase:           LowerCase,
+		interfaces:           make(map[reflect.Type]*interfaceRegistration),
+		decoders:             make(map[reflect.Type]DecodeFunc),
+		encoders:             make(map[reflect.Type]EncodeFunc),
+		timeLayout:           time.RFC3339Nano,
+		bytesEncoding:        Base64,
+		listSeparator:        defaultListSeparator,
+		mapPairSeparator:     defaultMapPairSeparator,
+		mapKeyValueSeparator: defaultMapKeyValueSeparator,
+		separator:            defaultSeparator,
+		maxSliceLength:       defaultMaxSliceLength,
+	}
+	l.registerStandardTypes()
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.snapshot = &atomic.Pointer[MapSource]{}
+	l.Reload()
+	return l
+}
+
+// defaultTagSupports returns the tags looked at by default to know the name of a field.
+func defaultTagSupports() []string {
+	return []string{
+		"yaml", "json", "mapstructure",
 	}
-	return &Lamenv{
-		tagSupports: []string{
-			"yaml", "json", "mapstructure",
-		},
-		env: env,
+}
+
+// Reload takes a new snapshot of the variables available in the source.
+// The snapshot is what is used by Unmarshal, so the variables set after the creation of the Lamenv (or after the last
+// call to Reload) are not seen until Reload is called. See the option WithSnapshotPerCall to avoid calling it.
+// It can be called while other goroutines are using Unmarshal, every call keeps the snapshot it started with.
+func (l *Lamenv) Reload() {
+	if l.snapshot == nil {
+		// a Lamenv not created by New doesn't have any snapshot until the first call to Reload
+		l.setDefaults()
+		l.snapshot = &atomic.Pointer[MapSource]{}
 	}
+	snapshot := takeSnapshot(l.source)
+	l.snapshot.Store(&snapshot)
+}
+
+// takeSnapshot returns every variable available in the source with its value.
+func takeSnapshot(source Source) MapSource {
+	snapshot := make(MapSource)
+	for _, variable := range source.Variables() {
+		if value, ok := source.Lookup(variable); ok {
+			snapshot[variable] = value
+		}
+	}
+	return snapshot
 }
 
 // Unmarshal reads the object to guess and find the appropriate environment variable to use for the decoding.
 // Once the environment variable matching the field looked is found, it will unmarshall the value and the set the field with it.
+//
+// The same Lamenv can be used by several goroutines at the same time, as long as it is not modified by one of its
+// exported method in the meantime.
 func (l *Lamenv) Unmarshal(object interface{}, parts []string) error {
-	return l.decode(reflect.ValueOf(object), parts)
+	call := l.newCall()
+	parts = call.partsOrPrefix(parts)
+	if call.strict && len(call.buildEnvVariable(parts)) == 0 {
+		// every variable of the source would have to be used, including the ones like PATH or HOME
+		return fmt.Errorf("the strict mode requires a prefix to know which variables must be used")
+	}
+	if err := call.decode(reflect.ValueOf(object), parts, nil); err != nil {
+		return err
+	}
+	if call.strict {
+		return call.checkUnusedVariables(parts)
+	}
+	return nil
+}
+
+// newCall returns a copy of the configuration reading the variables from a single snapshot and holding its own list
+// of variables not used yet. That's what is modified while decoding, so the Lamenv itself stays untouched and can be
+// reused. Since every lookup goes through the same snapshot, a call sees a consistent environment.
+func (l *Lamenv) newCall() *Lamenv {
+	call := *l
+	call.setDefaults()
+	var snapshot MapSource
+	if l.snapshot == nil || l.snapshotPerCall {
+		// a Lamenv not created by New doesn't have any snapshot
+		snapshot = takeSnapshot(call.source)
+	} else {
+		snapshot = *l.snapshot.Load()
+	}
+	call.source = snapshot
+	call.env = make(map[string]bool, len(snapshot))
+	for variable := range snapshot {
+		call.env[variable] = true
+	}
+	return &call
+}
+
+// setDefaults sets the configuration that is missing when the Lamenv has not been created by New, like when using
+// its zero value. It includes the registration of the standard types, so the same input is decoded the same way.
+func (l *Lamenv) setDefaults() {
+	if l.tagSupports == nil {
+		l.tagSupports = defaultTagSupports()
+	}
+	if l.interfaces == nil {
+		l.interfaces = make(map[reflect.Type]*interfaceRegistration)
+	}
+	if l.decoders == nil || l.encoders == nil {
+		l.decoders = make(map[reflect.Type]DecodeFunc)
+		l.encoders = make(map[reflect.Type]EncodeFunc)
+		l.registerStandardTypes()
+	}
+	if l.source == nil {
+		l.source = OSSource
+	}
+	if len(l.separator) == 0 {
+		l.separator = defaultSeparator
+	}
+	if l.mapKeyCase == nil {
+		l.mapKeyCase = LowerCase
+	}
+	if len(l.timeLayout) == 0 {
+		l.timeLayout = time.RFC3339Nano
+	}
+	if len(l.bytesEncoding) == 0 {
+		l.bytesEncoding = Base64
+	}
+	if len(l.listSeparator) == 0 {
+		l.listSeparator = defaultListSeparator
+	}
+	if len(l.mapPairSeparator) == 0 {
+		l.mapPairSeparator = defaultMapPairSeparator
+	}
+	if len(l.mapKeyValueSeparator) == 0 {
+		l.mapKeyValueSeparator = defaultMapKeyValueSeparator
+	}
+	if l.maxSliceLength <= 0 {
+		l.maxSliceLength = defaultMaxSliceLength
+	}
+}
+
+// checkUnusedVariables returns an error listing the variables starting with the parts that have not been used.
+func (l *Lamenv) checkUnusedVariables(parts []string) error {
+	prefix := l.buildEnvVariable(parts)
+	var unused []string
+	for variable := range l.env {
+		if variable == prefix || strings.HasPrefix(variable, prefix+l.separator) {
+			unused = append(unused, variable)
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	sort.Strings(unused)
+	return fmt.Errorf("the following variables are not used: %s", strings.Join(unused, ", "))
 }
 
 func (l *Lamenv) Marshal(object interface{}, parts []string) error {
-	return l.encode(reflect.ValueOf(object), parts)
+	call := *l
+	call.setDefaults()
+	return call.encode(reflect.ValueOf(object), call.partsOrPrefix(parts), nil)
+}
+
+// partsOrPrefix returns the prefix set with the option WithPrefix when no parts are provided.
+func (l *Lamenv) partsOrPrefix(parts []string) []string {
+	if len(parts) == 0 {
+		return l.prefix
+	}
+	return parts
 }
 
 // AddTagSupport modify the current tag list supported by adding the one passed as a parameter.
 // If you prefer to override the default tag list supported by Lamenv, use the method OverrideTagSupport instead.
 func (l *Lamenv) AddTagSupport(tags ...string) *Lamenv {
+	l.setDefaults()
 	l.tagSupports = append(l.tagSupports, tags...)
 	return l
 }
@@ -156,38 +502,335 @@ func (l *Lamenv) AddTagSupport(tags ...string) *Lamenv {
 // OverrideTagSupport overrides the current tag list supported by the one passed as a parameter.
 // If you prefer to add new tag supported instead of overriding the current list, use the method AddTagSupport instead.
 func (l *Lamenv) OverrideTagSupport(tags ...string) *Lamenv {
-	l.tagSupports = tags
+	// the list is never nil, otherwise the default one would be used
+	l.tagSupports = append([]string{}, tags...)
+	return l
+}
+
+// MapKeyCase changes the policy used to set the key of a map when unmarshalling it. By default, the keys are lowercased.
+// Note: it doesn't have any impact on the marshalling, since an environment variable is always uppercased.
+func (l *Lamenv) MapKeyCase(keyCase KeyCase) *Lamenv {
+	l.mapKeyCase = keyCase
+	return l
+}
+
+// InferScalarTypes changes the way a value is decoded when the type to decode is an empty interface.
+// By default, the value is kept as a string. When enabled, the value is converted to an int, a float64 or a bool
+// if it's possible.
+func (l *Lamenv) InferScalarTypes(infer bool) *Lamenv {
+	l.inferScalarTypes = infer
+	return l
+}
+
+// RegisterInterface declares the concrete types that can be used to unmarshal a field typed with the interface ifaceType.
+// The concrete type is chosen according to the value of the environment variable <PREFIX>_<DISCRIMINATOR_FIELD>.
+// The same discriminator is set when marshalling the interface.
+//
+// Example of how to use it with the following environment variables available:
+//
+//	MY_PREFIX_EXPORTER_TYPE = otlp
+//	MY_PREFIX_EXPORTER_ENDPOINT = localhost:4317
+//
+//	type Exporter interface {
+//		Export() error
+//	}
+//	type T struct {
+//		Exporter Exporter `yaml:"exporter"`
+//	}
+//	var t T
+//	lamenv.New().
+//		RegisterInterface(reflect.TypeOf((*Exporter)(nil)).Elem(), "type", map[string]reflect.Type{
+//			"otlp": reflect.TypeOf(&OTLPExporter{}),
+//			"file": reflect.TypeOf(&FileExporter{}),
+//		}).
+//		Unmarshal(&t, []string{"MY_PREFIX"})
+//
+// It panics if ifaceType is not an interface or if one of the types doesn't implement it.
+func (l *Lamenv) RegisterInterface(ifaceType reflect.Type, discriminatorField string, types map[string]reflect.Type) *Lamenv {
+	if ifaceType.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("lamenv: %s is not an interface", ifaceType))
+	}
+	for name, t := range types {
+		if !t.Implements(ifaceType) {
+			panic(fmt.Sprintf("lamenv: the type %s registered with the name %q doesn't implement %s", t, name, ifaceType))
+		}
+	}
+	l.setDefaults()
+	l.interfaces[ifaceType] = &interfaceRegistration{
+		discriminator: discriminatorField,
+		types:         types,
+	}
+	return l
+}
+
+// RegisterDecoder registers a function to decode the type t.
+// It has the priority over the interfaces Unmarshaler and encoding.TextUnmarshaler,
+// which makes it possible to support a type you don't own without using a wrapper type.
+// When a pointer to t is met, the function is used to decode the element of the pointer.
+//
+//	lamenv.New().RegisterDecoder(reflect.TypeOf(&regexp.Regexp{}), func(input string) (interface{}, error) {
+//		return regexp.Compile(input)
+//	})
+func (l *Lamenv) RegisterDecoder(t reflect.Type, decoder DecodeFunc) *Lamenv {
+	l.setDefaults()
+	l.decoders[t] = decoder
+	return l
+}
+
+// RegisterEncoder registers a function to encode the type t. It is the symmetric of the method RegisterDecoder.
+func (l *Lamenv) RegisterEncoder(t reflect.Type, encoder EncodeFunc) *Lamenv {
+	l.setDefaults()
+	l.encoders[t] = encoder
+	return l
+}
+
+// AddDecodeHook appends the hooks to the chain applied on the value of an environment variable before parsing it
+// into a native type (string, bool, int, uint, float).
+// The hooks are applied in the order they have been added. See DecodeHookFunc to know how the chain works.
+func (l *Lamenv) AddDecodeHook(hooks ...DecodeHookFunc) *Lamenv {
+	l.decodeHooks = append(l.decodeHooks, hooks...)
+	return l
+}
+
+// TimeLayout changes the layout used to decode and encode a time.Time. By default, it is time.RFC3339Nano, which is
+// also accepting a time without fractional seconds.
+func (l *Lamenv) TimeLayout(layout string) *Lamenv {
+	l.timeLayout = layout
+	return l
+}
+
+// BytesEncoding changes the encoding used by default for a []byte or a [N]byte. By default, it is Base64.
+// The encoding can also be chosen per field by adding its name as a flag in the tag "lamenv". For example:
+//
+//	Cert []byte `yaml:"cert" lamenv:"hex"`
+func (l *Lamenv) BytesEncoding(encoding BytesEncoding) *Lamenv {
+	l.bytesEncoding = encoding
+	return l
+}
+
+// DelimitedList allows every slice of scalar (string, bool, int, etc.) to be provided as a single variable where the items
+// are separated by a comma (or the separator set with ListSeparator). It's then also the syntax used when marshalling.
+// An item can contain the separator if it is escaped by a backslash or if it is surrounded by double quotes:
+//
+//	MY_PREFIX_ORIGINS=https://a.com,"https://b.com,c",https://d.com\,e
+//
+// The delimited variable replaces the current content of the slice, then the indexed variables (if any) override the
+// items by index. To enable it only for a specific field, add the flag "list" in its tag "lamenv":
+//
+//	Origins []string `yaml:"origins" lamenv:"list"`
+//
+// Note: when the slice is the value of a map, only the delimited syntax can be used to guess the key of the map.
+func (l *Lamenv) DelimitedList(enable bool) *Lamenv {
+	l.delimitedList = enable
+	return l
+}
+
+// ListSeparator changes the separator used between the items of a delimited variable. By default, it's a comma.
+func (l *Lamenv) ListSeparator(separator string) *Lamenv {
+	l.listSeparator = separator
+	return l
+}
+
+// InlineMap allows every map of scalar (string, bool, int, etc.) to be provided as a single variable containing the
+// pairs of key/value. By default, the pairs are separated by a comma and the key from the value by an equal sign.
+// Like for a delimited list, a separator can be escaped by a backslash or surrounded by double quotes.
+// It's then also the syntax used when marshalling.
+//
+//	MY_PREFIX_LABELS=team=core,tier=1
+//
+// The inline variable is decoded first, then the variables per key (like MY_PREFIX_LABELS_TEAM) can override it.
+// Since the key is in the value of the variable, its casing is kept and the KeyCase policy is not applied.
+// To enable it only for a specific field, add the flag "kv" in its tag "lamenv":
+//
+//	Labels map[string]string `yaml:"labels" lamenv:"kv"`
+//
+// Note: when the map is itself in the value of another map, the inline syntax cannot be used to guess the key of the
+// parent map.
+func (l *Lamenv) InlineMap(enable bool) *Lamenv {
+	l.inlineMap = enable
+	return l
+}
+
+// MapSeparators changes the separators used in an inline map between the pairs of key/value and between the key and the value.
+func (l *Lamenv) MapSeparators(pairSeparator string, keyValueSeparator string) *Lamenv {
+	l.mapPairSeparator = pairSeparator
+	l.mapKeyValueSeparator = keyValueSeparator
+	return l
+}
+
+// JSONValue allows every struct, slice or map to be provided as a single variable containing a JSON document,
+// decoded with encoding/json (so using the "json" tags of the type). The document is only decoded if it looks like
+// a JSON object or array.
+//
+//	MY_PREFIX_RULES=[{"name": "a"}, {"name": "b"}]
+//
+// The JSON document is decoded first, then the other variables (like MY_PREFIX_RULES_0_NAME) can override it.
+// To enable it only for a specific field, add the flag "json" in its tag "lamenv". In this case, the field is also
+// marshalled as a JSON document:
+//
+//	Rules []Rule `yaml:"rules" lamenv:"json"`
+func (l *Lamenv) JSONValue(enable bool) *Lamenv {
+	l.jsonValue = enable
+	return l
+}
+
+// SliceGaps changes the way to treat the gaps between the indexes of a slice. By default, it is StopAtGap.
+func (l *Lamenv) SliceGaps(policy SliceGapPolicy) *Lamenv {
+	l.sliceGapPolicy = policy
 	return l
 }
 
+// SliceLength allows the variable <PREFIX>_LEN to declare the length of a slice. The slice is then resized to this length,
+// the missing indexes being left to the zero value. It is also a way to declare explicitly an empty slice:
+//
+//	MY_PREFIX_HOSTS_LEN=0
+//
+// An error is returned when an index is exceeding the declared length.
+func (l *Lamenv) SliceLength(enable bool) *Lamenv {
+	l.sliceLength = enable
+	return l
+}
+
+// MaxSliceLength changes the maximum length of a slice that can be declared by the variable <PREFIX>_LEN, or reached by
+// an index when the policy is FillGaps. By default (or when max is lower or equal to zero), it is 10000.
+// Since the slice is allocated with this length, it prevents a single variable from exhausting the memory.
+// An error naming the variable is returned when the length is exceeded.
+func (l *Lamenv) MaxSliceLength(max int) *Lamenv {
+	l.maxSliceLength = max
+	return l
+}
+
+// Merge changes the way to decode a slice or a map that is already populated. By default, it is MergeByIndex.
+// The strategy can also be chosen per field by adding "merge", "replace" or "append" as a flag in the tag "lamenv":
+//
+//	Hosts []string `yaml:"hosts" lamenv:"replace"`
+func (l *Lamenv) Merge(strategy MergeStrategy) *Lamenv {
+	l.mergeStrategy = strategy
+	return l
+}
+
+func (l *Lamenv) mergeStrategyOf(flags []string) MergeStrategy {
+	switch {
+	case containStr(flags, mergeByIndex):
+		return MergeByIndex
+	case containStr(flags, replace):
+		return Replace
+	case containStr(flags, appendItems):
+		return Append
+	}
+	return l.mergeStrategy
+}
+
+func (l *Lamenv) bytesEncodingOf(flags []string) BytesEncoding {
+	for _, encoding := range []BytesEncoding{Base64, Base64URL, Hex, Raw} {
+		if containStr(flags, string(encoding)) {
+			return encoding
+		}
+	}
+	return l.bytesEncoding
+}
+
 func (l *Lamenv) lookupTag(tag reflect.StructTag) ([]string, bool) {
 	return lookupTag(tag, l.tagSupports)
 }
 
-func contains(parts []string) bool {
-	variable := buildEnvVariable(parts)
-	for _, e := range os.Environ() {
-		envSplit := strings.SplitN(e, "=", 2)
-		if len(envSplit) != 2 {
-			continue
+// lookupFlags returns the flags of the field coming from the tag "lamenv".
+func lookupFlags(tag reflect.StructTag) []string {
+	s, ok := tag.Lookup(flagTag)
+	if !ok {
+		return nil
+	}
+	flags := strings.Split(s, ",")
+	for i, flag := range flags {
+		flags[i] = strings.TrimSpace(flag)
+	}
+	return flags
+}
+
+func (l *Lamenv) contains(parts []string) bool {
+	variable := l.buildEnvVariable(parts)
+	for _, e := range l.source.Variables() {
+		if strings.Contains(e, variable) {
+			return true
 		}
-		if strings.Contains(envSplit[0], variable) {
+	}
+	return false
+}
+
+// containsVariable returns true if the variable built from the parts exists, or if one variable is starting with it
+// followed by an underscore.
+func (l *Lamenv) containsVariable(parts []string) bool {
+	variable := l.buildEnvVariable(parts)
+	for _, e := range l.source.Variables() {
+		if e == variable || strings.HasPrefix(e, variable+l.separator) {
 			return true
 		}
 	}
 	return false
 }
 
+// findIndexes returns the sorted list of indexes found in the environment variables looking like <PREFIX>_<INDEX>(_<SUFFIX>)?
+func (l *Lamenv) findIndexes(parts []string) []int {
+	prefix := l.buildEnvVariable(parts)
+	if len(prefix) > 0 {
+		prefix = prefix + l.separator
+	}
+	found := make(map[int]bool)
+	for _, e := range l.source.Variables() {
+		if !strings.HasPrefix(e, prefix) {
+			continue
+		}
+		segment := strings.SplitN(strings.TrimPrefix(e, prefix), l.separator, 2)[0]
+		if len(segment) == 0 || strings.TrimLeft(segment, "0123456789") != "" {
+			continue
+		}
+		// an index like 01 is ignored, since it won't be the variable looked at when decoding the index 1
+		if index, err := strconv.Atoi(segment); err == nil && strconv.Itoa(index) == segment {
+			found[index] = true
+		}
+	}
+	result := make([]int, 0, len(found))
+	for index := range found {
+		result = append(result, index)
+	}
+	sort.Ints(result)
+	return result
+}
+
 // lookupEnv is returning:
 // 1. the name of the environment variable
 // 2. the value of the environment variable
 // 3. if the environment variable exists
-func lookupEnv(parts []string) (string, string, bool) {
-	variable := buildEnvVariable(parts)
-	value, ok := os.LookupEnv(variable)
+func (l *Lamenv) lookupEnv(parts []string) (string, string, bool) {
+	variable := l.buildEnvVariable(parts)
+	value, ok := l.source.Lookup(variable)
 	return variable, value, ok
 }
 
+// markUsed removes the variable from the ones that are still not used, to avoid reusing it later.
+func (l *Lamenv) markUsed(variable string) {
+	delete(l.env, variable)
+	l.logf("the variable %s is used", variable)
+}
+
+func (l *Lamenv) logf(format string, v ...interface{}) {
+	if l.logger != nil {
+		l.logger.Printf(format, v...)
+	}
+}
+
+// setEnv is the only place where an environment variable is written when marshalling.
+func (l *Lamenv) setEnv(variable string, value string) error {
+	if l.sink != nil {
+		l.sink[variable] = value
+		if l.sensitive {
+			l.sensitiveVariables[variable] = true
+		}
+		return nil
+	}
+	return os.Setenv(variable, value)
+}
+
 func lookupTag(tag reflect.StructTag, tagSupports []string) ([]string, bool) {
 	for _, tagSupport := range tagSupports {
 		if s, ok := tag.Lookup(tagSupport); ok {
@@ -197,12 +840,12 @@ func lookupTag(tag reflect.StructTag, tagSupports []string) ([]string, bool) {
 	return nil, false
 }
 
-func buildEnvVariable(parts []string) string {
+func (l *Lamenv) buildEnvVariable(parts []string) string {
 	newParts := make([]string, len(parts))
 	for i, s := range parts {
 		newParts[i] = strings.ToUpper(s)
 	}
-	return strings.Join(newParts, "_")
+	return strings.Join(newParts, l.separator)
 }
 
 // containStr returns true if s is one element of series
@@ -3,12 +3,18 @@ package lamenv
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/netip"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -30,6 +36,9 @@ func (s *dummyString) MarshalText() ([]byte, error) {
 	return []byte(strings.Replace(string(*s), "bar", "foo", -1)), nil
 }
 
+// dummyKey is a named string used as a key of a map.
+type dummyKey string
+
 func TestUnmarshalWithOverriding(t *testing.T) {
 	type InlineStruct struct {
 		A string `json:"a"`
@@ -584,6 +593,43 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "map of map",
+			config: &struct {
+				Regions map[string]map[string]struct {
+					Port int
+				}
+				Map map[string][]map[string]int
+			}{},
+			env: map[string]string{
+				"REGIONS_EU_WEST__API_PORT":  "80",
+				"REGIONS_EU_WEST__AUTH_PORT": "81",
+				"REGIONS_US__API_PORT":       "82",
+				"MAP_A_0_B":                  "1",
+				"MAP_A_1_C":                  "2",
+			},
+			result: &struct {
+				Regions map[string]map[string]struct {
+					Port int
+				}
+				Map map[string][]map[string]int
+			}{
+				Regions: map[string]map[string]struct {
+					Port int
+				}{
+					"eu_west": {
+						"api":  {Port: 80},
+						"auth": {Port: 81},
+					},
+					"us": {
+						"api": {Port: 82},
+					},
+				},
+				Map: map[string][]map[string]int{
+					"a": {{"b": 1}, {"c": 2}},
+				},
+			},
+		},
 		{
 			title: "squash",
 			config: &struct {
@@ -743,7 +789,7 @@ func TestMarshal(t *testing.T) {
 				"B2":    "123",
 				"B3":    "456789",
 				"C":     "true",
-				"D":     "1.000000",
+				"D":     "1",
 				"TITLE": "my title",
 			},
 		},
@@ -784,7 +830,7 @@ func TestMarshal(t *testing.T) {
 				"B_2":   "123",
 				"B3":    "456789",
 				"C":     "true",
-				"D":     "1.000000",
+				"D":     "1",
 				"TITLE": "my title",
 			},
 		},
@@ -906,7 +952,7 @@ func TestMarshal(t *testing.T) {
 			},
 			result: map[string]string{
 				"MAP_LOL":        "5",
-				"MAP2_SUPER_FUN": "1.000000",
+				"MAP2_SUPER_FUN": "1",
 			},
 		},
 		{
@@ -1038,6 +1084,57 @@ func TestMarshal(t *testing.T) {
 				"E",
 			},
 		},
+		{
+			title: "map of map",
+			conf: &struct {
+				Regions map[string]map[string]struct {
+					Port int
+				}
+				Map map[string][]map[string]int
+			}{
+				Regions: map[string]map[string]struct {
+					Port int
+				}{
+					"eu_west": {
+						"api":  {Port: 80},
+						"auth": {Port: 81},
+					},
+					"us": {
+						"api": {Port: 82},
+					},
+				},
+				Map: map[string][]map[string]int{
+					"a": {{"b": 1}, {"c": 2}},
+				},
+			},
+			result: map[string]string{
+				"REGIONS_EU_WEST__API_PORT":  "80",
+				"REGIONS_EU_WEST__AUTH_PORT": "81",
+				"REGIONS_US__API_PORT":       "82",
+				"MAP_A_0_B":                  "1",
+				"MAP_A_1_C":                  "2",
+			},
+		},
+		{
+			title: "interface",
+			conf: &struct {
+				Plugin interface{}
+				Empty  interface{}
+			}{
+				Plugin: map[string]interface{}{
+					"name":  "my-plugin",
+					"hosts": []interface{}{"a", "b"},
+				},
+			},
+			result: map[string]string{
+				"PLUGIN_NAME":    "my-plugin",
+				"PLUGIN_HOSTS_0": "a",
+				"PLUGIN_HOSTS_1": "b",
+			},
+			resultNotExist: []string{
+				"EMPTY",
+			},
+		},
 		{
 			title: "squash",
 			conf: &struct {
@@ -1145,3 +1242,980 @@ func TestLamenv_OverrideTagSupport(t *testing.T) {
 	lam.OverrideTagSupport("env")
 	assert.Equal(t, []string{"env"}, lam.tagSupports)
 }
+
+func TestLamenv_MapKeyCase(t *testing.T) {
+	testSuites := []struct {
+		title   string
+		keyCase KeyCase
+		env     map[string]string
+		result  map[string]int
+	}{
+		{
+			title:   "default lowercase",
+			keyCase: LowerCase,
+			env: map[string]string{
+				"MAP_SERVICEA": "1",
+			},
+			result: map[string]int{
+				"servicea": 1,
+			},
+		},
+		{
+			title:   "uppercase",
+			keyCase: UpperCase,
+			env: map[string]string{
+				"MAP_SERVICEA": "1",
+			},
+			result: map[string]int{
+				"SERVICEA": 1,
+			},
+		},
+		{
+			title:   "preserve case",
+			keyCase: PreserveCase("ServiceA", "serviceB_Backup"),
+			env: map[string]string{
+				"MAP_SERVICEA":         "1",
+				"MAP_SERVICEB_BACKUP":  "2",
+				"MAP_UNKNOWN_SERVICEC": "3",
+			},
+			result: map[string]int{
+				"ServiceA":         1,
+				"serviceB_Backup":  2,
+				"unknown_servicec": 3,
+			},
+		},
+		{
+			title: "custom",
+			keyCase: func(key string) string {
+				return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+			},
+			env: map[string]string{
+				"MAP_SERVICE_A": "1",
+			},
+			result: map[string]int{
+				"service-a": 1,
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			for k, v := range test.env {
+				_ = os.Setenv(k, v)
+			}
+			config := &struct {
+				Map map[string]int
+			}{}
+			err := New().MapKeyCase(test.keyCase).Unmarshal(config, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, config.Map)
+			for k := range test.env {
+				_ = os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestMapWithNonStringKey(t *testing.T) {
+	type config struct {
+		Int   map[int]string
+		Uint  map[uint8]int
+		Bool  map[bool]string
+		Text  map[dummyString]int
+		Named map[dummyKey]int
+	}
+	c := &config{
+		Int:   map[int]string{1: "one", 42: "forty-two"},
+		Uint:  map[uint8]int{8: 64},
+		Bool:  map[bool]string{true: "yes", false: "no"},
+		Text:  map[dummyString]int{"bar": 1},
+		Named: map[dummyKey]int{"key": 2},
+	}
+	env := map[string]string{
+		"PREFIX_INT_1":      "one",
+		"PREFIX_INT_42":     "forty-two",
+		"PREFIX_UINT_8":     "64",
+		"PREFIX_BOOL_TRUE":  "yes",
+		"PREFIX_BOOL_FALSE": "no",
+		"PREFIX_TEXT_FOO":   "1",
+		"PREFIX_NAMED_KEY":  "2",
+	}
+	assert.NoError(t, Marshal(c, []string{"PREFIX"}))
+	for k, v := range env {
+		assert.Equal(t, v, os.Getenv(k))
+	}
+	result := &config{}
+	assert.NoError(t, Unmarshal(result, []string{"PREFIX"}))
+	assert.Equal(t, c, result)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	_ = os.Setenv("PREFIX_INT_NOT_A_NUMBER", "1")
+	assert.Error(t, Unmarshal(&config{}, []string{"PREFIX"}))
+	_ = os.Unsetenv("PREFIX_INT_NOT_A_NUMBER")
+}
+
+func TestUnmarshalSchemaless(t *testing.T) {
+	type config struct {
+		Plugin  interface{}            `json:"plugin"`
+		Plugins map[string]interface{} `json:"plugins"`
+		List    []interface{}          `json:"list"`
+		Scalar  interface{}            `json:"scalar"`
+		Empty   interface{}            `json:"empty"`
+	}
+	env := map[string]string{
+		"PREFIX_PLUGIN_NAME":        "my-plugin",
+		"PREFIX_PLUGIN_PORT":        "8080",
+		"PREFIX_PLUGIN_HOSTS_0":     "a",
+		"PREFIX_PLUGIN_HOSTS_2":     "c",
+		"PREFIX_PLUGIN_TLS_ENABLED": "true",
+		"PREFIX_PLUGINS_A_RATIO":    "0.5",
+		"PREFIX_PLUGINS_B":          "false",
+		"PREFIX_LIST_0_NAME":        "first",
+		"PREFIX_LIST_1":             "second",
+		"PREFIX_SCALAR":             "value",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+
+	testSuites := []struct {
+		title  string
+		infer  bool
+		result *config
+	}{
+		{
+			title: "without type inference",
+			result: &config{
+				Plugin: map[string]interface{}{
+					"name":  "my-plugin",
+					"port":  "8080",
+					"hosts": []interface{}{"a", nil, "c"},
+					"tls": map[string]interface{}{
+						"enabled": "true",
+					},
+				},
+				Plugins: map[string]interface{}{
+					"a": map[string]interface{}{
+						"ratio": "0.5",
+					},
+					"b": "false",
+				},
+				List: []interface{}{
+					map[string]interface{}{
+						"name": "first",
+					},
+					"second",
+				},
+				Scalar: "value",
+			},
+		},
+		{
+			title: "with type inference",
+			infer: true,
+			result: &config{
+				Plugin: map[string]interface{}{
+					"name":  "my-plugin",
+					"port":  8080,
+					"hosts": []interface{}{"a", nil, "c"},
+					"tls": map[string]interface{}{
+						"enabled": true,
+					},
+				},
+				Plugins: map[string]interface{}{
+					"a": map[string]interface{}{
+						"ratio": 0.5,
+					},
+					"b": false,
+				},
+				List: []interface{}{
+					map[string]interface{}{
+						"name": "first",
+					},
+					"second",
+				},
+				Scalar: "value",
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			c := &config{}
+			assert.NoError(t, New().InferScalarTypes(test.infer).Unmarshal(c, []string{"PREFIX"}))
+			assert.Equal(t, test.result, c)
+		})
+	}
+}
+
+func TestUnmarshalSchemalessConflict(t *testing.T) {
+	_ = os.Setenv("PREFIX_PLUGIN_A", "1")
+	_ = os.Setenv("PREFIX_PLUGIN_A_B", "2")
+	defer func() {
+		_ = os.Unsetenv("PREFIX_PLUGIN_A")
+		_ = os.Unsetenv("PREFIX_PLUGIN_A_B")
+	}()
+	c := &struct {
+		Plugin interface{}
+	}{}
+	assert.Error(t, Unmarshal(c, []string{"PREFIX"}))
+}
+
+func TestUnmarshalSchemalessSparseIndexes(t *testing.T) {
+	c := &struct {
+		Huge      interface{}
+		Sparse    interface{}
+		Padded    interface{}
+		Canonical interface{}
+	}{}
+	l := New(WithSource(MapSource{
+		"HUGE_9223372036854775806": "1",
+		"SPARSE_0":                 "a",
+		"SPARSE_5":                 "b",
+		"PADDED_01":                "a",
+		"PADDED_1":                 "b",
+		"CANONICAL_0":              "a",
+		"CANONICAL_2":              "c",
+	}))
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, map[string]interface{}{"9223372036854775806": "1"}, c.Huge)
+	assert.Equal(t, map[string]interface{}{"0": "a", "5": "b"}, c.Sparse)
+	assert.Equal(t, map[string]interface{}{"01": "a", "1": "b"}, c.Padded)
+	assert.Equal(t, []interface{}{"a", nil, "c"}, c.Canonical)
+}
+
+func TestUnmarshalMapOfNonEmptyInterface(t *testing.T) {
+	// without any registered type, the interface cannot be decoded, so it's not considered as a nested map
+	c := &struct {
+		M map[string]fmt.Stringer
+	}{}
+	l := New(WithSource(MapSource{"P_M_A__B": "x"}))
+	assert.Error(t, l.Unmarshal(c, []string{"p"}))
+	assert.Nil(t, c.M)
+}
+
+type exporter interface {
+	Name() string
+}
+
+type otlpExporter struct {
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+func (e *otlpExporter) Name() string {
+	return "otlp"
+}
+
+type fileExporter struct {
+	Path string `yaml:"path"`
+}
+
+func (e fileExporter) Name() string {
+	return "file"
+}
+
+func newExporterLamenv() *Lamenv {
+	return New().RegisterInterface(reflect.TypeOf((*exporter)(nil)).Elem(), "type", map[string]reflect.Type{
+		"otlp": reflect.TypeOf(&otlpExporter{}),
+		"file": reflect.TypeOf(fileExporter{}),
+	})
+}
+
+func TestRegisterInterface(t *testing.T) {
+	type config struct {
+		Exporter  exporter            `yaml:"exporter"`
+		Exporters map[string]exporter `yaml:"exporters"`
+		Default   exporter            `yaml:"default"`
+	}
+	env := map[string]string{
+		"PREFIX_EXPORTER_TYPE":           "otlp",
+		"PREFIX_EXPORTER_ENDPOINT":       "localhost:4317",
+		"PREFIX_EXPORTERS_MAIN_TYPE":     "otlp",
+		"PREFIX_EXPORTERS_MAIN_ENDPOINT": "remote:4317",
+		"PREFIX_EXPORTERS_MAIN_INSECURE": "true",
+		"PREFIX_EXPORTERS_LOCAL_FS_TYPE": "file",
+		"PREFIX_EXPORTERS_LOCAL_FS_PATH": "/tmp/traces",
+		"PREFIX_DEFAULT_PATH":            "/var/traces",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{
+		// no discriminator set for this field, so the concrete value should be completed
+		Default: fileExporter{Path: "/tmp"},
+	}
+	expected := &config{
+		Exporter: &otlpExporter{Endpoint: "localhost:4317"},
+		Exporters: map[string]exporter{
+			"main":     &otlpExporter{Endpoint: "remote:4317", Insecure: true},
+			"local_fs": fileExporter{Path: "/tmp/traces"},
+		},
+		Default: fileExporter{Path: "/var/traces"},
+	}
+	assert.NoError(t, newExporterLamenv().Unmarshal(c, []string{"PREFIX"}))
+	assert.Equal(t, expected, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, newExporterLamenv().Marshal(expected, []string{"PREFIX"}))
+	env["PREFIX_DEFAULT_TYPE"] = "file"
+	env["PREFIX_EXPORTER_INSECURE"] = "false"
+	for k, v := range env {
+		assert.Equal(t, v, os.Getenv(k), k)
+		_ = os.Unsetenv(k)
+	}
+
+	_ = os.Setenv("PREFIX_EXPORTER_TYPE", "unknown")
+	assert.Error(t, newExporterLamenv().Unmarshal(&config{}, []string{"PREFIX"}))
+	_ = os.Unsetenv("PREFIX_EXPORTER_TYPE")
+}
+
+func TestRegisterDecoderAndEncoder(t *testing.T) {
+	type config struct {
+		Pattern  *regexp.Regexp            `yaml:"pattern"`
+		Prefix   netip.Prefix              `yaml:"prefix"`
+		Patterns map[string]*regexp.Regexp `yaml:"patterns"`
+	}
+	newLamenv := func() *Lamenv {
+		return New().
+			RegisterDecoder(reflect.TypeOf(&regexp.Regexp{}), func(input string) (interface{}, error) {
+				return regexp.Compile(input)
+			}).
+			RegisterEncoder(reflect.TypeOf(&regexp.Regexp{}), func(value interface{}) (string, error) {
+				return value.(*regexp.Regexp).String(), nil
+			}).
+			RegisterDecoder(reflect.TypeOf(netip.Prefix{}), func(input string) (interface{}, error) {
+				return netip.ParsePrefix(input)
+			}).
+			RegisterEncoder(reflect.TypeOf(netip.Prefix{}), func(value interface{}) (string, error) {
+				return value.(netip.Prefix).String(), nil
+			})
+	}
+	env := map[string]string{
+		"PREFIX_PATTERN":            "^a+$",
+		"PREFIX_PREFIX":             "10.0.0.0/8",
+		"PREFIX_PATTERNS_FIRST_ONE": "b*",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{}
+	assert.NoError(t, newLamenv().Unmarshal(c, []string{"PREFIX"}))
+	assert.Equal(t, regexp.MustCompile("^a+$"), c.Pattern)
+	assert.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), c.Prefix)
+	assert.Equal(t, map[string]*regexp.Regexp{"first_one": regexp.MustCompile("b*")}, c.Patterns)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, newLamenv().Marshal(c, []string{"PREFIX"}))
+	for k, v := range env {
+		assert.Equal(t, v, os.Getenv(k))
+		_ = os.Unsetenv(k)
+	}
+
+	_ = os.Setenv("PREFIX_PATTERN", "(")
+	assert.Error(t, newLamenv().Unmarshal(&config{}, []string{"PREFIX"}))
+	_ = os.Unsetenv("PREFIX_PATTERN")
+}
+
+func TestUnmarshalIntegerWithBasePrefix(t *testing.T) {
+	type config struct {
+		Hexa   int    `yaml:"hexa"`
+		Octal  uint32 `yaml:"octal"`
+		Binary int8   `yaml:"binary"`
+		Under  int64  `yaml:"under"`
+		Neg    int16  `yaml:"neg"`
+		Padded int    `yaml:"padded"`
+		Port   uint16 `yaml:"port"`
+		Zero   int    `yaml:"zero"`
+		NegPad int    `yaml:"neg_pad"`
+	}
+	env := map[string]string{
+		"HEXA":    "0x1F",
+		"OCTAL":   "0o755",
+		"BINARY":  "0b101",
+		"UNDER":   "1_000_000",
+		"NEG":     "-0x10",
+		"PADDED":  "010",
+		"PORT":    "0080",
+		"ZERO":    "00",
+		"NEG_PAD": "-010",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, &config{Hexa: 31, Octal: 493, Binary: 5, Under: 1000000, Neg: -16, Padded: 10, Port: 80, NegPad: -10}, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+}
+
+func TestUnmarshalIntegerOverflow(t *testing.T) {
+	testSuites := []struct {
+		title  string
+		config interface{}
+		value  string
+	}{
+		{
+			title: "int8",
+			config: &struct {
+				Value int8
+			}{},
+			value: "128",
+		},
+		{
+			title: "uint16",
+			config: &struct {
+				Value uint16
+			}{},
+			value: "65536",
+		},
+		{
+			title: "negative uint",
+			config: &struct {
+				Value uint
+			}{},
+			value: "-1",
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			_ = os.Setenv("PREFIX_VALUE", test.value)
+			err := Unmarshal(test.config, []string{"PREFIX"})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "PREFIX_VALUE")
+			_ = os.Unsetenv("PREFIX_VALUE")
+		})
+	}
+	_ = os.Setenv("PREFIX_VALUE", "128")
+	err := Unmarshal(&struct{ Value int8 }{}, []string{"PREFIX"})
+	assert.EqualError(t, err, `unable to decode the variable PREFIX_VALUE: the value "128" overflows int8`)
+	_ = os.Unsetenv("PREFIX_VALUE")
+}
+
+type nativeKinds struct {
+	String     string
+	Bool       bool
+	Int        int
+	Int8       int8
+	Int16      int16
+	Int32      int32
+	Int64      int64
+	Uint       uint
+	Uint8      uint8
+	Uint16     uint16
+	Uint32     uint32
+	Uint64     uint64
+	Float32    float32
+	Float64    float64
+	Complex64  complex64
+	Complex128 complex128
+	Duration   time.Duration
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	parts := []string{"ROUND_TRIP"}
+	roundTrip := func(value nativeKinds) bool {
+		if strings.ContainsRune(value.String, 0) {
+			// an environment variable cannot contain a NUL character
+			return true
+		}
+		defer func() {
+			for _, e := range os.Environ() {
+				if strings.HasPrefix(e, "ROUND_TRIP_") {
+					_ = os.Unsetenv(strings.SplitN(e, "=", 2)[0])
+				}
+			}
+		}()
+		if err := Marshal(&value, parts); err != nil {
+			t.Log(err)
+			return false
+		}
+		result := nativeKinds{}
+		if err := Unmarshal(&result, parts); err != nil {
+			t.Log(err)
+			return false
+		}
+		return assert.Equal(t, value, result)
+	}
+	assert.NoError(t, quick.Check(roundTrip, nil))
+	// quick doesn't generate the edge cases of float
+	assert.True(t, roundTrip(nativeKinds{Float32: 1e-9, Float64: 0.1 + 0.2}))
+	assert.True(t, roundTrip(nativeKinds{Float32: float32(math.Inf(1)), Float64: math.Inf(-1)}))
+	assert.True(t, roundTrip(nativeKinds{Float32: math.MaxFloat32, Float64: math.SmallestNonzeroFloat64}))
+
+	value := nativeKinds{Float64: math.NaN()}
+	assert.NoError(t, Marshal(&value, parts))
+	assert.Equal(t, "NaN", os.Getenv("ROUND_TRIP_FLOAT64"))
+	result := nativeKinds{}
+	assert.NoError(t, Unmarshal(&result, parts))
+	assert.True(t, math.IsNaN(result.Float64))
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, "ROUND_TRIP_") {
+			_ = os.Unsetenv(strings.SplitN(e, "=", 2)[0])
+		}
+	}
+}
+
+func TestLamenv_SliceGaps(t *testing.T) {
+	env := map[string]string{
+		"LIST_0":         "a",
+		"LIST_2":         "c",
+		"LIST_4":         "e",
+		"STRUCTS_1_NAME": "b",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	type config struct {
+		List    []string
+		Structs []struct {
+			Name string
+		}
+	}
+	testSuites := []struct {
+		title  string
+		policy SliceGapPolicy
+		result *config
+	}{
+		{
+			title:  "stop at gap",
+			policy: StopAtGap,
+			result: &config{
+				List: []string{"a"},
+			},
+		},
+		{
+			title:  "compact gaps",
+			policy: CompactGaps,
+			result: &config{
+				List: []string{"a", "c", "e"},
+				Structs: []struct {
+					Name string
+				}{{Name: "b"}},
+			},
+		},
+		{
+			title:  "fill gaps",
+			policy: FillGaps,
+			result: &config{
+				List: []string{"a", "", "c", "", "e"},
+				Structs: []struct {
+					Name string
+				}{{}, {Name: "b"}},
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			c := &config{}
+			assert.NoError(t, New().SliceGaps(test.policy).Unmarshal(c, nil))
+			assert.Equal(t, test.result, c)
+		})
+	}
+	err := New().SliceGaps(ErrorOnGap).Unmarshal(&config{}, nil)
+	assert.EqualError(t, err, "the index 1 of the variable LIST is missing")
+}
+
+func TestLamenv_SliceLength(t *testing.T) {
+	env := map[string]string{
+		"LIST_LEN":  "3",
+		"LIST_0":    "a",
+		"EMPTY_LEN": "0",
+		"SHORT_LEN": "1",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	type config struct {
+		List  []string
+		Empty []string
+		Short []string
+	}
+	c := &config{
+		Empty: []string{"from yaml"},
+		Short: []string{"x", "y"},
+	}
+	assert.NoError(t, New().SliceLength(true).Unmarshal(c, nil))
+	assert.Equal(t, &config{
+		List:  []string{"a", "", ""},
+		Empty: []string{},
+		Short: []string{"x"},
+	}, c)
+
+	_ = os.Setenv("LIST_3", "d")
+	defer os.Unsetenv("LIST_3")
+	assert.Error(t, New().SliceLength(true).SliceGaps(FillGaps).Unmarshal(&config{}, nil))
+
+	// with the default policy, the declared length keeps the indexes after a gap and still refuses the ones exceeding it
+	c = &config{}
+	l := New(WithSource(MapSource{"LIST_LEN": "3", "LIST_2": "z"}), WithSliceLength(true))
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, []string{"", "", "z"}, c.List)
+	l = New(WithSource(MapSource{"LIST_LEN": "1", "LIST_4": "z"}), WithSliceLength(true))
+	assert.EqualError(t, l.Unmarshal(&config{}, nil), "the index 4 of the variable LIST exceeds the declared length 1")
+}
+
+func TestLamenv_MaxSliceLength(t *testing.T) {
+	type config struct {
+		List []string
+	}
+	testSuites := []struct {
+		title string
+		l     *Lamenv
+		err   string
+	}{
+		{
+			title: "huge index filled",
+			l:     New(WithSource(MapSource{"LIST_9223372036854775806": "a"}), WithSliceGaps(FillGaps)),
+			err:   "unable to decode the variable LIST: the index 9223372036854775806 exceeds the maximum length 10000 of a slice",
+		},
+		{
+			title: "huge declared length",
+			l:     New(WithSource(MapSource{"LIST_LEN": "9223372036854775807"}), WithSliceLength(true)),
+			err:   "unable to decode the variable LIST_LEN: the length 9223372036854775807 exceeds the maximum length 10000 of a slice",
+		},
+		{
+			title: "custom maximum",
+			l:     New(WithSource(MapSource{"LIST_LEN": "11"}), WithSliceLength(true), WithMaxSliceLength(10)),
+			err:   "unable to decode the variable LIST_LEN: the length 11 exceeds the maximum length 10 of a slice",
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			assert.EqualError(t, test.l.Unmarshal(&config{}, nil), test.err)
+		})
+	}
+	c := &config{}
+	assert.NoError(t, New(WithSource(MapSource{"LIST_LEN": "10"}), WithSliceLength(true), WithMaxSliceLength(10)).Unmarshal(c, nil))
+	assert.Len(t, c.List, 10)
+}
+
+func TestLamenv_Merge(t *testing.T) {
+	env := map[string]string{
+		"HOSTS_0":     "c",
+		"LABELS_TEAM": "core",
+		"REPLACED_0":  "c",
+		"APPENDED_0":  "c",
+		"APPENDED_1":  "d",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	type config struct {
+		Hosts     []string          `yaml:"hosts"`
+		Labels    map[string]string `yaml:"labels"`
+		Replaced  []string          `yaml:"replaced" lamenv:"replace"`
+		Appended  []string          `yaml:"appended" lamenv:"append"`
+		Untouched []string          `yaml:"untouched"`
+	}
+	newConfig := func() *config {
+		return &config{
+			Hosts:     []string{"a", "b"},
+			Labels:    map[string]string{"tier": "1"},
+			Replaced:  []string{"a", "b"},
+			Appended:  []string{"a", "b"},
+			Untouched: []string{"a", "b"},
+		}
+	}
+	testSuites := []struct {
+		title    string
+		strategy MergeStrategy
+		result   *config
+	}{
+		{
+			title:    "merge by index",
+			strategy: MergeByIndex,
+			result: &config{
+				Hosts:     []string{"c", "b"},
+				Labels:    map[string]string{"tier": "1", "team": "core"},
+				Replaced:  []string{"c"},
+				Appended:  []string{"a", "b", "c", "d"},
+				Untouched: []string{"a", "b"},
+			},
+		},
+		{
+			title:    "replace",
+			strategy: Replace,
+			result: &config{
+				Hosts:     []string{"c"},
+				Labels:    map[string]string{"team": "core"},
+				Replaced:  []string{"c"},
+				Appended:  []string{"a", "b", "c", "d"},
+				Untouched: []string{"a", "b"},
+			},
+		},
+		{
+			title:    "append",
+			strategy: Append,
+			result: &config{
+				Hosts:     []string{"a", "b", "c"},
+				Labels:    map[string]string{"tier": "1", "team": "core"},
+				Replaced:  []string{"c"},
+				Appended:  []string{"a", "b", "c", "d"},
+				Untouched: []string{"a", "b"},
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			c := newConfig()
+			assert.NoError(t, New().Merge(test.strategy).Unmarshal(c, nil))
+			assert.Equal(t, test.result, c)
+		})
+	}
+}
+
+func TestLamenv_MergeOverYAML(t *testing.T) {
+	type config struct {
+		Hosts  []string          `yaml:"hosts" lamenv:"replace"`
+		Ports  []int             `yaml:"ports" lamenv:"append"`
+		Labels map[string]string `yaml:"labels" lamenv:"merge"`
+	}
+	c := &config{}
+	// the flags specific to lamenv must not prevent yaml.v3 from reading the struct
+	assert.NoError(t, yaml.Unmarshal([]byte("hosts: [a, b]\nports: [80]\nlabels: {tier: \"1\"}\n"), c))
+	l := New(WithSource(MapSource{
+		"APP_HOSTS_0":     "c",
+		"APP_PORTS_0":     "443",
+		"APP_LABELS_TEAM": "core",
+	}))
+	assert.NoError(t, l.Unmarshal(c, []string{"app"}))
+	assert.Equal(t, &config{
+		Hosts:  []string{"c"},
+		Ports:  []int{80, 443},
+		Labels: map[string]string{"tier": "1", "team": "core"},
+	}, c)
+}
+
+// endpoint reads its own variables and declares them, so it can be used as the value of a map.
+type endpoint struct {
+	address string
+}
+
+func (e *endpoint) UnmarshalEnv(parts []string) error {
+	host := os.Getenv(strings.ToUpper(strings.Join(append(parts, "host"), "_")))
+	port := os.Getenv(strings.ToUpper(strings.Join(append(parts, "port"), "_")))
+	e.address = host + ":" + port
+	return nil
+}
+
+func (e *endpoint) EnvShape() []string {
+	return []string{"HOST", "PORT"}
+}
+
+func TestUnmarshalerInMap(t *testing.T) {
+	env := map[string]string{
+		"ENDPOINTS_EU_WEST_HOST": "eu.example.com",
+		"ENDPOINTS_EU_WEST_PORT": "443",
+		"ENDPOINTS_US_HOST":      "us.example.com",
+		"ENDPOINTS_US_PORT":      "8443",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	c := &struct {
+		Endpoints map[string]endpoint
+	}{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, map[string]endpoint{
+		"eu_west": {address: "eu.example.com:443"},
+		"us":      {address: "us.example.com:8443"},
+	}, c.Endpoints)
+}
+
+// upstream is read from the variable named by its prefix, and optionally from the variable <PREFIX>_HOST.
+type upstream struct {
+	name string
+	host string
+}
+
+func (u *upstream) UnmarshalEnvFrom(decoder *Decoder) error {
+	if name, ok := decoder.Lookup(); ok {
+		decoder.MarkUsed()
+		u.name = name
+	}
+	if host, ok := decoder.Lookup("host"); ok {
+		decoder.MarkUsed("host")
+		u.host = host
+	}
+	return nil
+}
+
+func (u *upstream) EnvShape() []string {
+	return []string{"", "HOST"}
+}
+
+func TestEnvShapeWithEmptySuffix(t *testing.T) {
+	c := &struct {
+		Upstreams map[string]upstream
+	}{}
+	l := New(WithSource(MapSource{
+		"UPSTREAMS_API":         "api",
+		"UPSTREAMS_API_HOST":    "api.local",
+		"UPSTREAMS_AUTH_V2":     "auth",
+		"UPSTREAMS_STATIC_HOST": "static.local",
+	}))
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, map[string]upstream{
+		"api":     {name: "api", host: "api.local"},
+		"auth_v2": {name: "auth"},
+		"static":  {host: "static.local"},
+	}, c.Upstreams)
+}
+
+func TestLamenv_ConcurrentUnmarshal(t *testing.T) {
+	type config struct {
+		Labels map[string]string
+		Hosts  []string
+	}
+	l := New(WithSource(MapSource{
+		"APP_LABELS_TEAM": "core",
+		"APP_LABELS_TIER": "1",
+		"APP_HOSTS_0":     "a",
+	}))
+	expected := &config{
+		Labels: map[string]string{"team": "core", "tier": "1"},
+		Hosts:  []string{"a"},
+	}
+	// a second call must see the same variables as the first one
+	for i := 0; i < 2; i++ {
+		c := &config{}
+		assert.NoError(t, l.Unmarshal(c, []string{"app"}))
+		assert.Equal(t, expected, c)
+	}
+	results := make(chan *config, 10)
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			c := &config{}
+			errs <- l.Unmarshal(c, []string{"app"})
+			results <- c
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, <-errs)
+		assert.Equal(t, expected, <-results)
+	}
+}
+
+func TestLamenv_Reload(t *testing.T) {
+	type config struct {
+		Labels map[string]string
+		Port   int
+	}
+	source := MapSource{
+		"LABELS_TEAM": "core",
+	}
+	l := New(WithSource(source))
+	perCall := New(WithSource(source), WithSnapshotPerCall(true))
+	source["LABELS_TIER"] = "1"
+	source["PORT"] = "8080"
+
+	c := &config{}
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, &config{Labels: map[string]string{"team": "core"}}, c)
+
+	expected := &config{Labels: map[string]string{"team": "core", "tier": "1"}, Port: 8080}
+	c = &config{}
+	assert.NoError(t, perCall.Unmarshal(c, nil))
+	assert.Equal(t, expected, c)
+
+	l.Reload()
+	c = &config{}
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, expected, c)
+}
+
+func TestLamenv_ZeroValue(t *testing.T) {
+	env := map[string]string{
+		"ZERO_PORT":          "8080",
+		"ZERO_LABELS_TEAM":   "core",
+		"ZERO_MODE":          "0755",
+		"ZERO_CODE":          "abc",
+		"ZERO_EXPORTER_TYPE": "file",
+		"ZERO_EXPORTER_PATH": "/tmp",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+		_ = os.Unsetenv("ZERO_NAME")
+		_ = os.Unsetenv("ZERO_CODE")
+	}()
+	type code string
+	type config struct {
+		Port     int
+		Labels   map[string]string `yaml:"labels"`
+		Name     string            `yaml:"name,omitempty"`
+		Mode     os.FileMode
+		Code     code
+		Exporter exporter
+	}
+	expected := &config{
+		Port:     8080,
+		Labels:   map[string]string{"team": "core"},
+		Mode:     0o755,
+		Code:     "ABC",
+		Exporter: fileExporter{Path: "/tmp"},
+	}
+	var l Lamenv
+	assert.NotPanics(t, func() {
+		l.RegisterDecoder(reflect.TypeOf(code("")), func(input string) (interface{}, error) {
+			return code(strings.ToUpper(input)), nil
+		}).RegisterEncoder(reflect.TypeOf(code("")), func(value interface{}) (string, error) {
+			return strings.ToLower(string(value.(code))), nil
+		}).RegisterInterface(reflect.TypeOf((*exporter)(nil)).Elem(), "type", map[string]reflect.Type{
+			"file": reflect.TypeOf(fileExporter{}),
+		})
+	})
+	c := &config{}
+	assert.NoError(t, l.Unmarshal(c, []string{"zero"}))
+	assert.Equal(t, expected, c)
+	// the zero value must decode the same input the same way as New
+	c = &config{}
+	var zero Lamenv
+	assert.NoError(t, zero.Unmarshal(&c.Mode, []string{"zero", "mode"}))
+	assert.Equal(t, os.FileMode(0o755), c.Mode)
+
+	assert.NotPanics(t, l.Reload)
+	c = &config{}
+	assert.NoError(t, l.Unmarshal(c, []string{"zero"}))
+	assert.Equal(t, expected, c)
+
+	assert.NoError(t, l.Marshal(&config{Name: "lamenv", Code: "XYZ"}, []string{"zero"}))
+	assert.Equal(t, "lamenv", os.Getenv("ZERO_NAME"))
+	assert.Equal(t, "xyz", os.Getenv("ZERO_CODE"))
+}
@@ -0,0 +1,161 @@
+package lamenv
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+)
+
+const (
+	// list is the flag used in a tag to accept a slice of scalar as a single delimited variable.
+	list = "list"
+	// defaultListSeparator is the separator used by default between the items of a delimited variable.
+	defaultListSeparator = ","
+	// keyValue is the flag used in a tag to accept a map of scalar as a single variable containing the pairs of key/value.
+	keyValue = "kv"
+	// defaultMapPairSeparator is the separator used by default between the pairs of key/value of an inline map.
+	defaultMapPairSeparator = ","
+	// defaultMapKeyValueSeparator is the separator used by default between the key and the value of an inline map.
+	defaultMapKeyValueSeparator = "="
+)
+
+// splitEscaped splits s around each separator that is neither escaped by a backslash nor between double quotes.
+// The items returned are still escaped, use unescape to get the actual value of each item.
+// If n >= 0, splitEscaped returns at most n items, the last one being the unsplit remainder.
+func splitEscaped(s string, sep string, n int) []string {
+	var result []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			// skip the character escaped
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], sep) && (n < 0 || len(result) < n-1):
+			result = append(result, s[start:i])
+			start = i + len(sep)
+			i = start - 1
+		}
+	}
+	return append(result, s[start:])
+}
+
+// unescape removes the backslashes used to escape a character and the double quotes surrounding a part of s.
+func unescape(s string) string {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				builder.WriteByte(s[i])
+			}
+		case '"':
+			continue
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return builder.String()
+}
+
+// escape adds a backslash in front of every backslash, double quote and separator, so s can be split safely by splitEscaped.
+func escape(s string, separators ...string) string {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' || s[i] == '"' {
+			builder.WriteByte('\\')
+			builder.WriteByte(s[i])
+			continue
+		}
+		for _, sep := range separators {
+			if len(sep) > 0 && strings.HasPrefix(s[i:], sep) {
+				builder.WriteByte('\\')
+				break
+			}
+		}
+		builder.WriteByte(s[i])
+	}
+	return builder.String()
+}
+
+// isScalar returns true if the type is decoded from a single environment variable without any suffix.
+func (l *Lamenv) isScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return l.isScalar(t.Elem())
+	}
+	if _, ok := l.decoders[t]; ok || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.Float32,
+		reflect.Float64,
+		reflect.Complex64,
+		reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// decodeScalar decodes the input into v, which must be a scalar as defined by isScalar.
+func (l *Lamenv) decodeScalar(v reflect.Value, input string) error {
+	if decoder, ok := l.decoders[v.Type()]; ok {
+		result, err := decoder(input)
+		if err != nil {
+			return err
+		}
+		return setHookResult(v, result)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return l.decodeScalar(v.Elem(), input)
+	}
+	if p, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return p.UnmarshalText([]byte(input))
+	}
+	return l.decodeNative(v, input)
+}
+
+// encodeScalar is the symmetric of decodeScalar.
+func (l *Lamenv) encodeScalar(v reflect.Value) (string, error) {
+	if encoder, ok := l.encoders[v.Type()]; ok {
+		return encoder(v.Interface())
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		return l.encodeScalar(v.Elem())
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if p, ok := ptr.Interface().(encoding.TextMarshaler); ok {
+		raw, err := p.MarshalText()
+		return string(raw), err
+	}
+	return nativeToString(v), nil
+}
+
+func (l *Lamenv) isDelimitedList(t reflect.Type, flags []string) bool {
+	return t.Kind() == reflect.Slice && (l.delimitedList || containStr(flags, list)) && l.isScalar(t.Elem())
+}
+
+func (l *Lamenv) isInlineMap(t reflect.Type, flags []string) bool {
+	return t.Kind() == reflect.Map && (l.inlineMap || containStr(flags, keyValue)) && isSupportedMapKey(t.Key()) && l.isScalar(t.Elem())
+}
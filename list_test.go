@@ -0,0 +1,206 @@
+package lamenv
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEscaped(t *testing.T) {
+	testSuites := []struct {
+		title  string
+		input  string
+		sep    string
+		result []string
+	}{
+		{
+			title:  "simple list",
+			input:  "a,b,c",
+			sep:    ",",
+			result: []string{"a", "b", "c"},
+		},
+		{
+			title:  "escaped separator",
+			input:  `a\,b,c`,
+			sep:    ",",
+			result: []string{"a,b", "c"},
+		},
+		{
+			title:  "quoted separator",
+			input:  `"a,b",c`,
+			sep:    ",",
+			result: []string{"a,b", "c"},
+		},
+		{
+			title:  "escaped quote and backslash",
+			input:  `a\"b;c\;d`,
+			sep:    ";",
+			result: []string{`a"b`, "c;d"},
+		},
+		{
+			title:  "separator with multiple characters",
+			input:  "a::b::c",
+			sep:    "::",
+			result: []string{"a", "b", "c"},
+		},
+		{
+			title:  "empty items",
+			input:  ",a,",
+			sep:    ",",
+			result: []string{"", "a", ""},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			items := splitEscaped(test.input, test.sep, -1)
+			result := make([]string, len(items))
+			for i, item := range items {
+				result[i] = unescape(item)
+			}
+			assert.Equal(t, test.result, result)
+			escapedItems := make([]string, len(test.result))
+			for i, item := range test.result {
+				escapedItems[i] = escape(item, test.sep)
+			}
+			assert.Equal(t, len(test.result), len(splitEscaped(strings.Join(escapedItems, test.sep), test.sep, -1)))
+		})
+	}
+	assert.Equal(t, []string{"a", "b=c"}, splitEscaped("a=b=c", "=", 2))
+}
+
+func TestDelimitedList(t *testing.T) {
+	type config struct {
+		Origins   []string        `yaml:"origins" lamenv:"list"`
+		Ports     []int           `yaml:"ports" lamenv:"list"`
+		Timeouts  []time.Duration `yaml:"timeouts" lamenv:"list"`
+		Indexed   []string        `yaml:"indexed"`
+		Overrides []string        `yaml:"overrides" lamenv:"list"`
+		Empty     []string        `yaml:"empty" lamenv:"list"`
+		Sparse    []string        `yaml:"sparse" lamenv:"list"`
+	}
+	env := map[string]string{
+		"ORIGINS":     `https://a.com,"https://b.com,c",https://d.com\,e`,
+		"PORTS":       "80, 443",
+		"TIMEOUTS":    "1s,1m",
+		"INDEXED":     "a,b",
+		"INDEXED_0":   "c",
+		"OVERRIDES":   "a,b,c",
+		"OVERRIDES_0": "z",
+		"OVERRIDES_1": "d",
+		"SPARSE":      "a,b,c",
+		"SPARSE_1":    "x",
+		"EMPTY":       "",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{
+		Overrides: []string{"1", "2", "3", "4", "5"},
+	}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, &config{
+		Origins:   []string{"https://a.com", "https://b.com,c", "https://d.com,e"},
+		Ports:     []int{80, 443},
+		Timeouts:  []time.Duration{time.Second, time.Minute},
+		Indexed:   []string{"c"},
+		Overrides: []string{"z", "d", "c"},
+		Empty:     []string{},
+		Sparse:    []string{"a", "x", "c"},
+	}, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, Marshal(&config{
+		Origins: []string{"https://a.com", "https://b.com,c", `quote"`},
+		Ports:   []int{80, 443},
+		Indexed: []string{"a", "b"},
+	}, nil))
+	assert.Equal(t, `https://a.com,https://b.com\,c,quote\"`, os.Getenv("ORIGINS"))
+	assert.Equal(t, "80,443", os.Getenv("PORTS"))
+	assert.Equal(t, "a", os.Getenv("INDEXED_0"))
+	for _, k := range []string{"ORIGINS", "PORTS", "INDEXED_0", "INDEXED_1"} {
+		_ = os.Unsetenv(k)
+	}
+}
+
+func TestLamenv_DelimitedList(t *testing.T) {
+	_ = os.Setenv("PREFIX_HOSTS", "a;b")
+	_ = os.Setenv("PREFIX_MAP_KEY", "c;d")
+	defer func() {
+		_ = os.Unsetenv("PREFIX_HOSTS")
+		_ = os.Unsetenv("PREFIX_MAP_KEY")
+	}()
+	c := &struct {
+		Hosts []string
+		Map   map[string][]string
+	}{}
+	assert.NoError(t, New().DelimitedList(true).ListSeparator(";").Unmarshal(c, []string{"PREFIX"}))
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	assert.Equal(t, map[string][]string{"key": {"c", "d"}}, c.Map)
+}
+
+func TestInlineMap(t *testing.T) {
+	type config struct {
+		Labels  map[string]string `yaml:"labels" lamenv:"kv"`
+		Weights map[string]int    `yaml:"weights" lamenv:"kv"`
+		Ports   map[int]bool      `yaml:"ports" lamenv:"kv"`
+		PerKey  map[string]string `yaml:"per_key"`
+	}
+	env := map[string]string{
+		"LABELS":      `Team=core,tier=1,query="a=b,c",escaped=d\,e`,
+		"LABELS_TIER": "2",
+		"WEIGHTS":     "a=1, b = 2",
+		"PORTS":       "80=true,443=false",
+		"PER_KEY":     "a=b",
+		"PER_KEY_KEY": "value",
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, &config{
+		Labels: map[string]string{
+			"Team":    "core",
+			"tier":    "2",
+			"query":   "a=b,c",
+			"escaped": "d,e",
+		},
+		Weights: map[string]int{"a": 1, "b": 2},
+		Ports:   map[int]bool{80: true, 443: false},
+		PerKey:  map[string]string{"key": "value"},
+	}, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, Marshal(&config{
+		Labels: map[string]string{
+			"team":  "core",
+			"query": "a=b,c",
+		},
+		Ports: map[int]bool{80: true},
+	}, nil))
+	assert.Equal(t, `query=a\=b\,c,team=core`, os.Getenv("LABELS"))
+	assert.Equal(t, "80=true", os.Getenv("PORTS"))
+	_ = os.Unsetenv("LABELS")
+	_ = os.Unsetenv("PORTS")
+
+	_ = os.Setenv("LABELS", "not a pair")
+	assert.Error(t, Unmarshal(&config{}, nil))
+	_ = os.Unsetenv("LABELS")
+}
+
+func TestLamenv_InlineMap(t *testing.T) {
+	_ = os.Setenv("LABELS", "team:core;tier:1")
+	defer os.Unsetenv("LABELS")
+	c := &struct {
+		Labels map[string]string
+	}{}
+	assert.NoError(t, New().InlineMap(true).MapSeparators(";", ":").Unmarshal(c, nil))
+	assert.Equal(t, map[string]string{"team": "core", "tier": "1"}, c.Labels)
+}
@@ -0,0 +1,27 @@
+package lamenv
+
+// Load decodes the environment variables into a new value of the type T.
+//
+// Example of how to use it with the following environment variables available:
+//
+//	MY_PREFIX_PORT = 8080
+//
+//	type Config struct {
+//		Port int `yaml:"port"`
+//	}
+//	config, err := lamenv.Load[Config](lamenv.WithPrefix("MY_PREFIX"), lamenv.WithStrict(true))
+func Load[T any](opts ...Option) (T, error) {
+	l := New(opts...)
+	var result T
+	err := l.Unmarshal(&result, l.prefix)
+	return result, err
+}
+
+// MustLoad is like Load but panics if the environment variables cannot be decoded.
+func MustLoad[T any](opts ...Option) T {
+	result, err := Load[T](opts...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package lamenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type loadConfig struct {
+	Port  int      `yaml:"port"`
+	Hosts []string `yaml:"hosts"`
+	Debug bool     `env:"verbose"`
+}
+
+func TestLoad(t *testing.T) {
+	source := MapSource{
+		"APP_PORT":    "8080",
+		"APP_HOSTS_0": "a",
+		"APP_HOSTS_1": "b",
+		"APP_VERBOSE": "true",
+	}
+	testSuites := []struct {
+		title  string
+		opts   []Option
+		result loadConfig
+		err    string
+	}{
+		{
+			title: "prefix and source",
+			opts:  []Option{WithPrefix("APP"), WithSource(source)},
+			result: loadConfig{
+				Port:  8080,
+				Hosts: []string{"a", "b"},
+			},
+		},
+		{
+			title: "tags",
+			opts:  []Option{WithPrefix("APP"), WithSource(source), WithTags("env", "yaml")},
+			result: loadConfig{
+				Port:  8080,
+				Hosts: []string{"a", "b"},
+				Debug: true,
+			},
+		},
+		{
+			title: "strict",
+			opts:  []Option{WithPrefix("APP"), WithSource(source), WithStrict(true)},
+			err:   "the following variables are not used: APP_VERBOSE",
+		},
+		{
+			title: "strict without prefix",
+			opts:  []Option{WithStrict(true)},
+			err:   "the strict mode requires a prefix to know which variables must be used",
+		},
+		{
+			title: "strict with every variable used",
+			opts:  []Option{WithPrefix("APP"), WithSource(source), WithTags("env", "yaml"), WithStrict(true)},
+			result: loadConfig{
+				Port:  8080,
+				Hosts: []string{"a", "b"},
+				Debug: true,
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Load[loadConfig](test.opts...)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestLoadFromOS(t *testing.T) {
+	_ = os.Setenv("LOAD_PORT", "9090")
+	defer os.Unsetenv("LOAD_PORT")
+	assert.Equal(t, loadConfig{Port: 9090}, MustLoad[loadConfig](WithPrefix("LOAD")))
+}
+
+func TestMustLoad(t *testing.T) {
+	assert.Panics(t, func() {
+		MustLoad[loadConfig](WithSource(MapSource{"PORT": "not a number"}))
+	})
+}
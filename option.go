@@ -0,0 +1,179 @@
+package lamenv
+
+import "reflect"
+
+// Option is a way to configure a Lamenv when it is created by New or Load.
+type Option func(l *Lamenv)
+
+// WithTags overrides the list of tags looked at to know the name of a field. See the method OverrideTagSupport.
+func WithTags(tags ...string) Option {
+	return func(l *Lamenv) {
+		l.OverrideTagSupport(tags...)
+	}
+}
+
+// WithPrefix sets the prefix of the environment variables. It is used by Unmarshal, Marshal and Diff when they are
+// called without any parts.
+func WithPrefix(parts ...string) Option {
+	return func(l *Lamenv) {
+		l.prefix = parts
+	}
+}
+
+// WithSource changes where the environment variables are read from. By default, it's OSSource.
+func WithSource(source Source) Option {
+	return func(l *Lamenv) {
+		l.source = source
+	}
+}
+
+// WithSnapshotPerCall takes a new snapshot of the variables available in the source at each call to Unmarshal,
+// instead of using the one taken by New (or by the last call to Reload).
+func WithSnapshotPerCall(enable bool) Option {
+	return func(l *Lamenv) {
+		l.snapshotPerCall = enable
+	}
+}
+
+// WithStrict makes the unmarshalling fail when a variable starting with the prefix has not been used to decode
+// the object. It's a way to catch a typo in the name of a variable.
+// A prefix is required, otherwise every variable of the source would have to be used. Without it, an error is returned.
+func WithStrict(strict bool) Option {
+	return func(l *Lamenv) {
+		l.strict = strict
+	}
+}
+
+// WithSeparator changes the separator used between the parts of an environment variable. By default, it's an underscore.
+// For example, with a double underscore, the field `yaml:"my_map"` of the struct `yaml:"app"` is read from APP__MY_MAP.
+func WithSeparator(separator string) Option {
+	return func(l *Lamenv) {
+		l.separator = separator
+	}
+}
+
+// WithMapKeyCase changes the policy used to set the key of a map. See the method MapKeyCase.
+func WithMapKeyCase(keyCase KeyCase) Option {
+	return func(l *Lamenv) {
+		l.MapKeyCase(keyCase)
+	}
+}
+
+// WithDecodeHooks appends the hooks to the chain applied on the value of an environment variable. See the method AddDecodeHook.
+func WithDecodeHooks(hooks ...DecodeHookFunc) Option {
+	return func(l *Lamenv) {
+		l.AddDecodeHook(hooks...)
+	}
+}
+
+// WithLogger sets the logger used to trace which variables are used and which keys of a map are guessed.
+func WithLogger(logger Logger) Option {
+	return func(l *Lamenv) {
+		l.logger = logger
+	}
+}
+
+// WithInferScalarTypes is the option equivalent to the method InferScalarTypes.
+func WithInferScalarTypes(infer bool) Option {
+	return func(l *Lamenv) {
+		l.InferScalarTypes(infer)
+	}
+}
+
+// WithInterface is the option equivalent to the method RegisterInterface.
+func WithInterface(ifaceType reflect.Type, discriminatorField string, types map[string]reflect.Type) Option {
+	return func(l *Lamenv) {
+		l.RegisterInterface(ifaceType, discriminatorField, types)
+	}
+}
+
+// WithDecoder is the option equivalent to the method RegisterDecoder.
+func WithDecoder(t reflect.Type, decoder DecodeFunc) Option {
+	return func(l *Lamenv) {
+		l.RegisterDecoder(t, decoder)
+	}
+}
+
+// WithEncoder is the option equivalent to the method RegisterEncoder.
+func WithEncoder(t reflect.Type, encoder EncodeFunc) Option {
+	return func(l *Lamenv) {
+		l.RegisterEncoder(t, encoder)
+	}
+}
+
+// WithTimeLayout is the option equivalent to the method TimeLayout.
+func WithTimeLayout(layout string) Option {
+	return func(l *Lamenv) {
+		l.TimeLayout(layout)
+	}
+}
+
+// WithBytesEncoding is the option equivalent to the method BytesEncoding.
+func WithBytesEncoding(encoding BytesEncoding) Option {
+	return func(l *Lamenv) {
+		l.BytesEncoding(encoding)
+	}
+}
+
+// WithDelimitedList is the option equivalent to the method DelimitedList.
+func WithDelimitedList(enable bool) Option {
+	return func(l *Lamenv) {
+		l.DelimitedList(enable)
+	}
+}
+
+// WithListSeparator is the option equivalent to the method ListSeparator.
+func WithListSeparator(separator string) Option {
+	return func(l *Lamenv) {
+		l.ListSeparator(separator)
+	}
+}
+
+// WithInlineMap is the option equivalent to the method InlineMap.
+func WithInlineMap(enable bool) Option {
+	return func(l *Lamenv) {
+		l.InlineMap(enable)
+	}
+}
+
+// WithMapSeparators is the option equivalent to the method MapSeparators.
+func WithMapSeparators(pairSeparator string, keyValueSeparator string) Option {
+	return func(l *Lamenv) {
+		l.MapSeparators(pairSeparator, keyValueSeparator)
+	}
+}
+
+// WithJSONValue is the option equivalent to the method JSONValue.
+func WithJSONValue(enable bool) Option {
+	return func(l *Lamenv) {
+		l.JSONValue(enable)
+	}
+}
+
+// WithSliceGaps is the option equivalent to the method SliceGaps.
+func WithSliceGaps(policy SliceGapPolicy) Option {
+	return func(l *Lamenv) {
+		l.SliceGaps(policy)
+	}
+}
+
+// WithSliceLength is the option equivalent to the method SliceLength.
+func WithSliceLength(enable bool) Option {
+	return func(l *Lamenv) {
+		l.SliceLength(enable)
+	}
+}
+
+// WithMaxSliceLength is the option equivalent to the method MaxSliceLength.
+func WithMaxSliceLength(max int) Option {
+	return func(l *Lamenv) {
+		l.MaxSliceLength(max)
+	}
+}
+
+// WithMerge is the option equivalent to the method Merge.
+func WithMerge(strategy MergeStrategy) Option {
+	return func(l *Lamenv) {
+		l.Merge(strategy)
+	}
+}
@@ -0,0 +1,85 @@
+package lamenv
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithSeparator(t *testing.T) {
+	type endpoint struct {
+		MaxConns int `yaml:"max_conns"`
+	}
+	type config struct {
+		LogLevel  string              `yaml:"log_level"`
+		Hosts     []string            `yaml:"hosts"`
+		Endpoints map[string]endpoint `yaml:"endpoints"`
+	}
+	l := New(
+		WithSeparator("__"),
+		WithSource(MapSource{
+			"APP__LOG_LEVEL":                     "debug",
+			"APP__HOSTS__0":                      "a",
+			"APP__HOSTS__1":                      "b",
+			"APP__ENDPOINTS__EU_WEST__MAX_CONNS": "10",
+		}),
+		WithStrict(true),
+	)
+	c := &config{}
+	assert.NoError(t, l.Unmarshal(c, []string{"app"}))
+	assert.Equal(t, &config{
+		LogLevel: "debug",
+		Hosts:    []string{"a", "b"},
+		Endpoints: map[string]endpoint{
+			"eu_west": {MaxConns: 10},
+		},
+	}, c)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l := New(
+		WithTags("env"),
+		WithMapKeyCase(UpperCase),
+		WithDecodeHooks(TrimSpaceHook()),
+		WithLogger(log.New(buffer, "", 0)),
+		WithSource(MapSource{
+			"NAME":        "  lamenv ",
+			"LABELS_TEAM": "core",
+		}),
+	)
+	c := &struct {
+		Title  string            `env:"name"`
+		Labels map[string]string `env:"labels"`
+	}{}
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, "lamenv", c.Title)
+	assert.Equal(t, map[string]string{"TEAM": "core"}, c.Labels)
+	assert.Equal(t, "the variable NAME is used\n"+
+		"the key TEAM of the map LABELS is guessed from the variable LABELS_TEAM\n"+
+		"the variable LABELS_TEAM is used\n", buffer.String())
+}
+
+func TestNewWithPrefix(t *testing.T) {
+	type config struct {
+		Port int `yaml:"port"`
+	}
+	l := New(WithPrefix("app"), WithSource(MapSource{
+		"PORT":     "80",
+		"APP_PORT": "8080",
+		"WEB_PORT": "9090",
+	}))
+	c := &config{}
+	assert.NoError(t, l.Unmarshal(c, nil))
+	assert.Equal(t, 8080, c.Port)
+	// the parts provided have the priority over the prefix
+	assert.NoError(t, l.Unmarshal(c, []string{"web"}))
+	assert.Equal(t, 9090, c.Port)
+
+	defer os.Unsetenv("APP_PORT")
+	assert.NoError(t, l.Marshal(&config{Port: 1}, nil))
+	assert.Equal(t, "1", os.Getenv("APP_PORT"))
+}
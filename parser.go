@@ -3,6 +3,7 @@ package lamenv
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -24,26 +25,42 @@ type ring struct {
 	children []*ring
 }
 
-func newRing(t reflect.Type, tag []string) *ring {
+func newRing(t reflect.Type, l *Lamenv) *ring {
 	root := &ring{
 		kind: root,
 	}
-	root.buildRing(t, tag)
+	root.buildRing(t, l, nil)
 	return root
 }
 
-func (r *ring) buildRing(t reflect.Type, tag []string) {
+// buildRing is looking at the type to build the children of the ring.
+// flags is the list of options coming from the tag of the field (if any) represented by the ring.
+func (r *ring) buildRing(t reflect.Type, l *Lamenv, flags []string) {
+	if _, ok := l.decoders[t]; ok || reflect.PtrTo(t).Implements(textUnmarshalerType) || isBytes(t) {
+		// a registered decoder, an encoding.TextUnmarshaler or a []byte only reads one variable
+		r.kind = leaf
+		return
+	}
+	if shaper, ok := reflect.New(t).Interface().(EnvShaper); ok {
+		r.buildShapeRing(shaper.EnvShape())
+		return
+	}
 	switch t.Kind() {
 	case reflect.Ptr:
-		r.buildRing(t.Elem(), tag)
+		r.buildRing(t.Elem(), l, flags)
 	case reflect.Slice,
 		reflect.Array:
+		if l.isDelimitedList(t, flags) {
+			// the slice is provided by a single variable
+			r.kind = leaf
+			return
+		}
 		if len(r.value) > 0 {
-			r.value = r.value + "_0"
+			r.value = r.value + l.separator + "0"
 		} else {
 			r.value = "0"
 		}
-		r.buildRing(t.Elem(), tag)
+		r.buildRing(t.Elem(), l, nil)
 	case reflect.Struct:
 		for i := 0; i < t.NumField(); i++ {
 			if len(t.Field(i).PkgPath) > 0 {
@@ -52,7 +69,7 @@ func (r *ring) buildRing(t reflect.Type, tag []string) {
 			}
 			field := t.Field(i)
 			var fieldName string
-			tags, ok := lookupTag(field.Tag, tag)
+			tags, ok := l.lookupTag(field.Tag)
 			kind := node
 			if ok {
 				fieldName = tags[0]
@@ -72,17 +89,78 @@ func (r *ring) buildRing(t reflect.Type, tag []string) {
 				kind:  kind,
 				value: strings.ToUpper(fieldName),
 			}
-			node.buildRing(field.Type, tag)
+			node.buildRing(field.Type, l, lookupFlags(field.Tag))
 			r.children = append(r.children, node)
 		}
-	case reflect.Map,
-		reflect.Interface:
+	case reflect.Interface:
+		if registration, ok := l.interfaces[t]; ok {
+			r.buildInterfaceRing(registration, l)
+		} else {
+			r.kind = deadLeaf
+		}
+	case reflect.Map:
 		r.kind = deadLeaf
 	default:
 		r.kind = leaf
 	}
 }
 
+// buildShapeRing uses the suffixes declared by a type implementing EnvShaper as the leaves of the ring.
+func (r *ring) buildShapeRing(suffixes []string) {
+	if len(suffixes) == 1 && len(suffixes[0]) == 0 {
+		// the type only reads the variable named by the prefix
+		r.kind = leaf
+		return
+	}
+	// otherwise, an empty suffix is a leaf without value, used by bfs when no other suffix is matching the variable.
+	for _, suffix := range suffixes {
+		r.children = append(r.children, &ring{
+			kind:  leaf,
+			value: strings.ToUpper(suffix),
+		})
+	}
+}
+
+// buildInterfaceRing merges the rings of every concrete type registered for the interface.
+// The discriminator is added as a leaf, since it's the variable used to know which concrete type to decode.
+func (r *ring) buildInterfaceRing(registration *interfaceRegistration, l *Lamenv) {
+	r.children = append(r.children, &ring{
+		kind:  leaf,
+		value: strings.ToUpper(registration.discriminator),
+	})
+	names := make([]string, 0, len(registration.types))
+	for name := range registration.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		concreteRing := &ring{
+			kind: nodeSquashed,
+		}
+		concreteRing.buildRing(registration.types[name], l, nil)
+		children := concreteRing.children
+		if concreteRing.kind != nodeSquashed {
+			// the concrete type is not a struct, so the ring itself is the path to use.
+			children = []*ring{concreteRing}
+		}
+		for _, child := range children {
+			// two concrete types can share the same field, but it shouldn't be considered as two possible paths.
+			if !r.containsChild(child) {
+				r.children = append(r.children, child)
+			}
+		}
+	}
+}
+
+func (r *ring) containsChild(child *ring) bool {
+	for _, c := range r.children {
+		if c.kind == child.kind && c.value == child.value {
+			return true
+		}
+	}
+	return false
+}
+
 type possiblePrefix struct {
 	// value is the actual value of the prefix
 	value string
@@ -92,13 +170,13 @@ type possiblePrefix struct {
 	endPos int
 }
 
-func findPrefixes(parts []string, pos int, value string) []possiblePrefix {
+func findPrefixes(parts []string, pos int, value string, sep string) []possiblePrefix {
 	var result []possiblePrefix
 	for i := pos; i < len(parts); i++ {
-		matched, p := consumePart(parts, i, value)
+		matched, p := consumePart(parts, i, value, sep)
 		if matched && i > 0 {
 			result = append(result, possiblePrefix{
-				value:    strings.Join(parts[:i], "_"),
+				value:    strings.Join(parts[:i], sep),
 				startPos: i,
 				endPos:   p,
 			})
@@ -107,11 +185,11 @@ func findPrefixes(parts []string, pos int, value string) []possiblePrefix {
 	return result
 }
 
-func consumePart(parts []string, pos int, value string) (bool, int) {
+func consumePart(parts []string, pos int, value string, sep string) (bool, int) {
 	aggregatedValue := parts[pos]
 	i := pos + 1
 	for i < len(parts) && aggregatedValue != value {
-		aggregatedValue = fmt.Sprintf("%s_%s", aggregatedValue, parts[i])
+		aggregatedValue = aggregatedValue + sep + parts[i]
 		i++
 	}
 	if aggregatedValue != value {
@@ -120,17 +198,43 @@ func consumePart(parts []string, pos int, value string) (bool, int) {
 	return true, i - 1
 }
 
+// isMapLevel returns true if the ring of the type t is directly a map (or a pointer to a map). An interface is excluded,
+// since it's either decoded without any schema, or not decodable at all.
+// In this case, there is no way to know where the key of the current map is finishing and where the one of the next map is starting.
+// That's why, to stay determinist, the key must be followed by an empty part, which means a double underscore in the environment variable.
+func (r *ring) isMapLevel(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return r.kind == deadLeaf && len(r.value) == 0 && t.Kind() == reflect.Map
+}
+
+// guessMapKey returns the key of a map when the value of the map is itself a map (see isMapLevel).
+func guessMapKey(parts []string, sep string) string {
+	// the key is closed by the first empty part, and there must be something after it, that would be the key of the next map
+	for i := 1; i < len(parts)-1; i++ {
+		if len(parts[i]) == 0 && len(parts[i+1]) > 0 {
+			return strings.Join(parts[:i], sep)
+		}
+	}
+	return ""
+}
+
 // guessPrefix is a way to determinate what is the missing prefix key that would complete the parts in order to have a complete ring
-func guessPrefix(parts []string, r *ring) (string, error) {
+func guessPrefix(parts []string, r *ring, sep string) (string, error) {
+	if r.kind == deadLeaf && len(r.value) > 0 {
+		// it's typically a slice of map, so the value is not empty and the bfs knows how to treat it.
+		return bfs(parts, r, sep)
+	}
 	if r.kind != root && r.kind != leaf {
 		return "", fmt.Errorf("unable to determinate the number of paths, ring is not the root or a leaf")
 	}
 
 	if r.kind == leaf {
 		if len(r.value) == 0 {
-			return strings.Join(parts, "_"), nil
+			return strings.Join(parts, sep), nil
 		} else {
-			prefixes := findPrefixes(parts, 0, r.value)
+			prefixes := findPrefixes(parts, 0, r.value, sep)
 			if len(prefixes) == 0 {
 				return "", nil
 			}
@@ -143,17 +247,24 @@ func guessPrefix(parts []string, r *ring) (string, error) {
 		}
 	}
 	// here we have to make a bfs (breadth-first search) into the tree that would stop once it doesn't find any child that has an empty value.
-	return bfs(parts, r)
+	return bfs(parts, r, sep)
 }
 
-func bfs(parts []string, r *ring) (string, error) {
+func bfs(parts []string, r *ring, sep string) (string, error) {
 	nodes := []*ring{r}
 	var result string
 	var paths uint64 = 0
+	// bare is true when a leaf without value has been found, like the empty suffix of an EnvShaper.
+	// It means the parts can be the key themselves, but only when no other path is matching them.
+	bare := false
 	for len(nodes) > 0 {
 		current := nodes[0]
 		// Remove the first element of the file, since it is currently treated
 		nodes = nodes[1:]
+		if len(current.value) == 0 && current.kind == leaf {
+			bare = true
+			continue
+		}
 		if len(current.value) == 0 {
 			// in that case we are putting all its children to be treated.
 			nodes = append(nodes, current.children...)
@@ -161,7 +272,7 @@ func bfs(parts []string, r *ring) (string, error) {
 			continue
 		}
 		// treatment of the current node
-		prefixes := findPrefixes(parts, 0, current.value)
+		prefixes := findPrefixes(parts, 0, current.value, sep)
 		switch current.kind {
 		case leaf:
 			for _, prefix := range prefixes {
@@ -196,7 +307,7 @@ func bfs(parts []string, r *ring) (string, error) {
 			// And hope there would one or less possible path at the end.
 			for _, prefix := range prefixes {
 				for _, child := range current.children {
-					pathPossibility(parts, prefix.endPos+1, child, &paths)
+					pathPossibility(parts, prefix.endPos+1, child, &paths, sep)
 					if paths > 1 {
 						return "", fmt.Errorf("too many possibilities available when choosing the key '%s'", prefix.value)
 					}
@@ -207,11 +318,14 @@ func bfs(parts []string, r *ring) (string, error) {
 			}
 		}
 	}
+	if paths == 0 && bare {
+		return strings.Join(parts, sep), nil
+	}
 	return result, nil
 }
 
 // pathPossibility will return the number of possible path depending of the available tree and the given parts
-func pathPossibility(parts []string, pos int, r *ring, result *uint64) {
+func pathPossibility(parts []string, pos int, r *ring, result *uint64, sep string) {
 	if len(r.value) > 0 {
 		if pos >= len(parts) {
 			// we are outside of the given parts, so it means there is no path that is matching the given parts
@@ -219,7 +333,7 @@ func pathPossibility(parts []string, pos int, r *ring, result *uint64) {
 		}
 		// here we have to determinate if value is a concatenation of multiple value of parts
 		// If it's not the case, then the path doesn't exist
-		matched, p := consumePart(parts, pos, r.value)
+		matched, p := consumePart(parts, pos, r.value, sep)
 		if !matched {
 			// as the value doesn't match any aggregation, then the path doesn't exist
 			return
@@ -242,7 +356,7 @@ func pathPossibility(parts []string, pos int, r *ring, result *uint64) {
 			nodeSquashed:
 			// if it is a node, then we just have to increase the position and restart the calculation for each child
 			for _, child := range r.children {
-				pathPossibility(parts, pos+1, child, result)
+				pathPossibility(parts, pos+1, child, result, sep)
 			}
 		}
 	} else {
@@ -258,7 +372,7 @@ func pathPossibility(parts []string, pos int, r *ring, result *uint64) {
 		case nodeSquashed:
 			// here we just have to ignore the current ring and move to the next one without increasing the position
 			for _, child := range r.children {
-				pathPossibility(parts, pos, child, result)
+				pathPossibility(parts, pos, child, result, sep)
 			}
 		case node:
 			// at this point, this case cannot exist, so it's better to say there is no path that would match this possibility
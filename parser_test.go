@@ -50,6 +50,33 @@ func TestNew(t *testing.T) {
 				children: nil,
 			},
 		},
+		{
+			title:  "bytes",
+			config: []byte("test"),
+			result: &ring{
+				kind:     leaf,
+				value:    "",
+				children: nil,
+			},
+		},
+		{
+			title:  "env shape",
+			config: endpoint{},
+			result: &ring{
+				kind:  root,
+				value: "",
+				children: []*ring{
+					{
+						kind:  leaf,
+						value: "HOST",
+					},
+					{
+						kind:  leaf,
+						value: "PORT",
+					},
+				},
+			},
+		},
 		{
 			title:  "leaf 5",
 			config: "test",
@@ -284,11 +311,37 @@ func TestNew(t *testing.T) {
 	for _, test := range testSuites {
 		t.Run(test.title, func(t *testing.T) {
 			v := reflect.TypeOf(test.config)
-			assert.Equal(t, test.result, newRing(v, defaultTagSupported))
+			assert.Equal(t, test.result, newRing(v, New()))
 		})
 	}
 }
 
+func TestNewWithRegisteredInterface(t *testing.T) {
+	r := newRing(reflect.TypeOf(map[string]exporter{}).Elem(), newExporterLamenv())
+	assert.Equal(t, &ring{
+		kind:  root,
+		value: "",
+		children: []*ring{
+			{
+				kind:  leaf,
+				value: "TYPE",
+			},
+			{
+				kind:  leaf,
+				value: "PATH",
+			},
+			{
+				kind:  leaf,
+				value: "ENDPOINT",
+			},
+			{
+				kind:  leaf,
+				value: "INSECURE",
+			},
+		},
+	}, r)
+}
+
 func TestPathPossibility(t *testing.T) {
 	testSuites := []struct {
 		title  string
@@ -457,7 +510,7 @@ func TestPathPossibility(t *testing.T) {
 	for _, test := range testSuites {
 		t.Run(test.title, func(t *testing.T) {
 			var r uint64 = 0
-			pathPossibility(strings.Split(test.part, "_"), 0, test.r, &r)
+			pathPossibility(strings.Split(test.part, "_"), 0, test.r, &r, "_")
 			assert.Equal(t, test.result, r)
 		})
 	}
@@ -506,7 +559,7 @@ func TestPossiblePrefix(t *testing.T) {
 	}
 	for _, test := range testSuites {
 		t.Run(test.title, func(t *testing.T) {
-			assert.Equal(t, test.result, findPrefixes(strings.Split(test.part, "_"), test.pos, test.value))
+			assert.Equal(t, test.result, findPrefixes(strings.Split(test.part, "_"), test.pos, test.value, "_"))
 		})
 	}
 }
@@ -558,12 +611,45 @@ func TestGuessPrefix(t *testing.T) {
 			part:   "a_b_0_0_0",
 			result: "a_b_0_0",
 		},
+		{
+			title: "map of slice of map",
+			r: &ring{
+				kind:  deadLeaf,
+				value: "0",
+			},
+			part:   "a_b_0_c",
+			result: "a_b",
+		},
 	}
 	for _, test := range testSuites {
 		t.Run(test.title, func(t *testing.T) {
-			prefix, err := guessPrefix(strings.Split(test.part, "_"), test.r)
+			prefix, err := guessPrefix(strings.Split(test.part, "_"), test.r, "_")
 			assert.NoError(t, err)
 			assert.Equal(t, test.result, prefix)
 		})
 	}
 }
+
+func TestGuessMapKey(t *testing.T) {
+	testSuites := []struct {
+		title  string
+		part   string
+		result string
+	}{
+		{
+			title:  "map of map",
+			part:   "a_b__c_d",
+			result: "a_b",
+		},
+		{
+			title:  "map of map without the key of the next map",
+			part:   "a_b__",
+			result: "",
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			assert.Equal(t, test.result, guessMapKey(strings.Split(test.part, "_"), "_"))
+		})
+	}
+}
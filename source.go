@@ -0,0 +1,56 @@
+package lamenv
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Source is where the environment variables are read from when unmarshalling. By default, it's the environment
+// of the process. The variables written when marshalling always go to the environment of the process.
+type Source interface {
+	// Lookup returns the value of the variable and if it exists.
+	Lookup(variable string) (string, bool)
+	// Variables returns the name of every variable available.
+	Variables() []string
+}
+
+// OSSource is the Source reading the environment of the process.
+var OSSource Source = osSource{}
+
+type osSource struct{}
+
+func (osSource) Lookup(variable string) (string, bool) {
+	return os.LookupEnv(variable)
+}
+
+func (osSource) Variables() []string {
+	environ := os.Environ()
+	variables := make([]string, 0, len(environ))
+	for _, e := range environ {
+		envSplit := strings.SplitN(e, "=", 2)
+		if len(envSplit) != 2 {
+			continue
+		}
+		variables = append(variables, envSplit[0])
+	}
+	return variables
+}
+
+// MapSource is a Source holding the variables in a map, with the name of the variable as a key.
+// It is useful for testing or when the variables are coming from somewhere else than the environment of the process.
+type MapSource map[string]string
+
+func (m MapSource) Lookup(variable string) (string, bool) {
+	value, ok := m[variable]
+	return value, ok
+}
+
+func (m MapSource) Variables() []string {
+	variables := make([]string, 0, len(m))
+	for variable := range m {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	return variables
+}
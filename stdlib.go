@@ -0,0 +1,111 @@
+package lamenv
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	urlType         = reflect.TypeOf(url.URL{})
+	urlPtrType      = reflect.TypeOf(&url.URL{})
+	ipNetType       = reflect.TypeOf(net.IPNet{})
+	ipNetPtrType    = reflect.TypeOf(&net.IPNet{})
+	regexpPtrType   = reflect.TypeOf(&regexp.Regexp{})
+	locationType    = reflect.TypeOf(time.Location{})
+	locationPtrType = reflect.TypeOf(&time.Location{})
+	fileModeType    = reflect.TypeOf(os.FileMode(0))
+)
+
+// registerStandardTypes registers the decoders and the encoders of the types coming from the standard library
+// that cannot be managed natively. The types implementing encoding.TextUnmarshaler like net.IP, netip.Addr,
+// netip.Prefix or big.Int don't need it.
+func (l *Lamenv) registerStandardTypes() {
+	l.RegisterDecoder(timeType, func(input string) (interface{}, error) {
+		return time.Parse(l.timeLayout, strings.TrimSpace(input))
+	})
+	l.RegisterEncoder(timeType, func(value interface{}) (string, error) {
+		return value.(time.Time).Format(l.timeLayout), nil
+	})
+
+	l.RegisterDecoder(urlPtrType, func(input string) (interface{}, error) {
+		return url.Parse(strings.TrimSpace(input))
+	})
+	l.RegisterEncoder(urlPtrType, func(value interface{}) (string, error) {
+		return value.(*url.URL).String(), nil
+	})
+	l.RegisterDecoder(urlType, func(input string) (interface{}, error) {
+		u, err := url.Parse(strings.TrimSpace(input))
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	})
+	l.RegisterEncoder(urlType, func(value interface{}) (string, error) {
+		u := value.(url.URL)
+		return u.String(), nil
+	})
+
+	l.RegisterDecoder(ipNetPtrType, func(input string) (interface{}, error) {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(input))
+		return ipNet, err
+	})
+	l.RegisterEncoder(ipNetPtrType, func(value interface{}) (string, error) {
+		return value.(*net.IPNet).String(), nil
+	})
+	l.RegisterDecoder(ipNetType, func(input string) (interface{}, error) {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(input))
+		if err != nil {
+			return nil, err
+		}
+		return *ipNet, nil
+	})
+	l.RegisterEncoder(ipNetType, func(value interface{}) (string, error) {
+		ipNet := value.(net.IPNet)
+		return ipNet.String(), nil
+	})
+
+	l.RegisterDecoder(regexpPtrType, func(input string) (interface{}, error) {
+		return regexp.Compile(input)
+	})
+	l.RegisterEncoder(regexpPtrType, func(value interface{}) (string, error) {
+		return value.(*regexp.Regexp).String(), nil
+	})
+
+	l.RegisterDecoder(locationPtrType, func(input string) (interface{}, error) {
+		return time.LoadLocation(strings.TrimSpace(input))
+	})
+	l.RegisterEncoder(locationPtrType, func(value interface{}) (string, error) {
+		return value.(*time.Location).String(), nil
+	})
+	l.RegisterDecoder(locationType, func(input string) (interface{}, error) {
+		location, err := time.LoadLocation(strings.TrimSpace(input))
+		if err != nil {
+			return nil, err
+		}
+		return *location, nil
+	})
+	l.RegisterEncoder(locationType, func(value interface{}) (string, error) {
+		location := value.(time.Location)
+		return location.String(), nil
+	})
+
+	// a file mode is always expressed in octal
+	l.RegisterDecoder(fileModeType, func(input string) (interface{}, error) {
+		mode, err := strconv.ParseUint(strings.TrimSpace(input), 8, 32)
+		if err != nil {
+			return nil, err
+		}
+		return os.FileMode(mode), nil
+	})
+	l.RegisterEncoder(fileModeType, func(value interface{}) (string, error) {
+		return fmt.Sprintf("%#o", uint32(value.(os.FileMode))), nil
+	})
+}
@@ -0,0 +1,113 @@
+package lamenv
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type standardTypesConfig struct {
+	Time      time.Time            `yaml:"time"`
+	URL       *url.URL             `yaml:"url"`
+	IP        net.IP               `yaml:"ip"`
+	IPNet     net.IPNet            `yaml:"ip_net"`
+	Addr      netip.Addr           `yaml:"addr"`
+	Prefix    netip.Prefix         `yaml:"prefix"`
+	Regexp    *regexp.Regexp       `yaml:"regexp"`
+	Location  *time.Location       `yaml:"location"`
+	Mode      os.FileMode          `yaml:"mode"`
+	BigInt    *big.Int             `yaml:"big_int"`
+	Complex64 complex64            `yaml:"complex_64"`
+	Complex   complex128           `yaml:"complex"`
+	Times     map[string]time.Time `yaml:"times"`
+}
+
+func TestStandardTypes(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	_, ipNet, _ := net.ParseCIDR("192.168.0.0/16")
+	env := map[string]string{
+		"PREFIX_TIME":          "2023-01-02T15:04:05Z",
+		"PREFIX_URL":           "https://example.com:8080/path?query=1",
+		"PREFIX_IP":            "10.0.0.1",
+		"PREFIX_IP_NET":        "192.168.0.0/16",
+		"PREFIX_ADDR":          "::1",
+		"PREFIX_PREFIX":        "10.0.0.0/8",
+		"PREFIX_REGEXP":        "^[a-z]+$",
+		"PREFIX_LOCATION":      "Europe/Paris",
+		"PREFIX_MODE":          "0755",
+		"PREFIX_BIG_INT":       "123456789012345678901234567890",
+		"PREFIX_COMPLEX_64":    "(1+2i)",
+		"PREFIX_COMPLEX":       "(1.5-3i)",
+		"PREFIX_TIMES_RELEASE": "2024-06-01T00:00:00Z",
+	}
+	expected := &standardTypesConfig{
+		Time: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		URL: &url.URL{
+			Scheme:   "https",
+			Host:     "example.com:8080",
+			Path:     "/path",
+			RawQuery: "query=1",
+		},
+		IP:        net.ParseIP("10.0.0.1"),
+		IPNet:     *ipNet,
+		Addr:      netip.MustParseAddr("::1"),
+		Prefix:    netip.MustParsePrefix("10.0.0.0/8"),
+		Regexp:    regexp.MustCompile("^[a-z]+$"),
+		Location:  location,
+		Mode:      0755,
+		BigInt:    bigInt,
+		Complex64: complex(1, 2),
+		Complex:   complex(1.5, -3),
+		Times: map[string]time.Time{
+			"release": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	c := &standardTypesConfig{}
+	assert.NoError(t, Unmarshal(c, []string{"PREFIX"}))
+	assert.Equal(t, expected, c)
+	for k := range env {
+		_ = os.Unsetenv(k)
+	}
+
+	assert.NoError(t, Marshal(expected, []string{"PREFIX"}))
+	for k, v := range env {
+		assert.Equal(t, v, os.Getenv(k), k)
+		_ = os.Unsetenv(k)
+	}
+}
+
+func TestLamenv_TimeLayout(t *testing.T) {
+	_ = os.Setenv("TIME", "2023-01-02")
+	defer os.Unsetenv("TIME")
+	c := &struct {
+		Time time.Time
+	}{}
+	assert.NoError(t, New().TimeLayout("2006-01-02").Unmarshal(c, nil))
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), c.Time)
+	assert.Error(t, Unmarshal(c, nil))
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	defer os.Unsetenv("TIME")
+	type config struct {
+		Time time.Time
+	}
+	expected := &config{Time: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)}
+	assert.NoError(t, Marshal(expected, nil))
+	assert.Equal(t, "2024-01-02T03:04:05.123456789Z", os.Getenv("TIME"))
+	c := &config{}
+	assert.NoError(t, Unmarshal(c, nil))
+	assert.Equal(t, expected, c)
+}
@@ -0,0 +1,212 @@
+package lamenv
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPollInterval is how often a FileSource is checked by default.
+const defaultPollInterval = time.Second
+
+// refresher is implemented by a source that can be read again, like FileSource.
+type refresher interface {
+	Refresh() (bool, error)
+}
+
+// Event is delivered to the subscribers of a Watcher each time the decoded value has changed.
+type Event[T any] struct {
+	// Previous is the value decoded before the change.
+	Previous *T
+	// Current is the value decoded after the change. It is the one returned by Watcher.Current.
+	Current *T
+	// Changes is the list of environment variables that are different between the two values. See Lamenv.Diff.
+	Changes []VariableChange
+}
+
+// Watcher decodes the environment variables into a value of the type T, and decodes them again into a fresh value
+// when the source changes. A change is detected when a signal is received, or when the file is modified if the source
+// is a FileSource.
+//
+// The new value replaces the previous one atomically, so Current can be called at any time by any goroutine.
+// A value is never modified once it has been returned by Current.
+//
+// Note: the changes are found by marshalling both values without touching the environment, which is not possible for
+// a type implementing Marshaler. The reload then fails, so such a type should implement EncoderMarshaler instead.
+type Watcher[T any] struct {
+	l       *Lamenv
+	current atomic.Pointer[T]
+	// reloadMutex serializes the reloads.
+	reloadMutex sync.Mutex
+	// mutex protects the subscribers, the error handler and the poll interval.
+	mutex        sync.Mutex
+	subscribers  []func(event Event[T])
+	onError      func(err error)
+	pollInterval time.Duration
+}
+
+// NewWatcher creates a Lamenv with the options provided and decodes the first value.
+//
+//	source, err := lamenv.NewFileSource("/etc/my-app/.env")
+//	watcher, err := lamenv.NewWatcher[Config](lamenv.WithSource(source), lamenv.WithPrefix("MY_APP"))
+//	watcher.Subscribe(func(event lamenv.Event[Config]) {
+//		log.Printf("%d variables changed", len(event.Changes))
+//	})
+//	go watcher.Watch(ctx, syscall.SIGHUP)
+func NewWatcher[T any](opts ...Option) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		l:            New(opts...),
+		pollInterval: defaultPollInterval,
+	}
+	value, err := w.decode()
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(value)
+	return w, nil
+}
+
+// Current returns the last value decoded.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe registers a function called each time the decoded value has changed.
+// The functions are called in the order they have been registered, by the goroutine that detected the change.
+func (w *Watcher[T]) Subscribe(subscriber func(event Event[T])) *Watcher[T] {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscribers = append(w.subscribers, subscriber)
+	return w
+}
+
+// OnError registers a function called when Watch is not able to decode the environment variables.
+// In this case, the previous value is kept.
+func (w *Watcher[T]) OnError(onError func(err error)) *Watcher[T] {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onError = onError
+	return w
+}
+
+// PollInterval changes how often the file is checked by Watch when the source is a FileSource. By default, it is one second.
+// A value lower or equal to zero disables the check.
+func (w *Watcher[T]) PollInterval(interval time.Duration) *Watcher[T] {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pollInterval = interval
+	return w
+}
+
+// Reload reads the source again and decodes the environment variables into a fresh value.
+// If the value has changed, it replaces the current one and the subscribers are notified.
+func (w *Watcher[T]) Reload() error {
+	if r, ok := w.l.source.(refresher); ok {
+		if _, err := r.Refresh(); err != nil {
+			return err
+		}
+	}
+	return w.reload()
+}
+
+// Watch blocks until the context is done. It reloads the value each time one of the signals is received and, when
+// the source is a FileSource, each time the file is modified.
+func (w *Watcher[T]) Watch(ctx context.Context, signals ...os.Signal) error {
+	var signalChan chan os.Signal
+	if len(signals) > 0 {
+		signalChan = make(chan os.Signal, 1)
+		signal.Notify(signalChan, signals...)
+		defer signal.Stop(signalChan)
+	}
+	var tick <-chan time.Time
+	w.mutex.Lock()
+	pollInterval := w.pollInterval
+	w.mutex.Unlock()
+	r, isRefresher := w.l.source.(refresher)
+	if isRefresher && pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signalChan:
+			w.handleError(w.Reload())
+		case <-tick:
+			modified, err := r.Refresh()
+			if err != nil {
+				w.handleError(err)
+				continue
+			}
+			if modified {
+				w.handleError(w.reload())
+			}
+		}
+	}
+}
+
+func (w *Watcher[T]) reload() error {
+	event, changed, err := w.swap()
+	if err != nil || !changed {
+		return err
+	}
+	// the subscribers are notified without holding any lock, so they are free to use the watcher.
+	w.mutex.Lock()
+	subscribers := make([]func(event Event[T]), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mutex.Unlock()
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+	return nil
+}
+
+// swap decodes a fresh value and replaces the current one if it has changed.
+func (w *Watcher[T]) swap() (Event[T], bool, error) {
+	w.reloadMutex.Lock()
+	defer w.reloadMutex.Unlock()
+	w.l.Reload()
+	value, err := w.decode()
+	if err != nil {
+		return Event[T]{}, false, err
+	}
+	previous := w.current.Load()
+	changes, err := w.l.Diff(previous, value, w.l.prefix)
+	if err != nil {
+		return Event[T]{}, false, err
+	}
+	if len(changes) == 0 {
+		return Event[T]{}, false, nil
+	}
+	w.current.Store(value)
+	return Event[T]{
+		Previous: previous,
+		Current:  value,
+		Changes:  changes,
+	}, true, nil
+}
+
+func (w *Watcher[T]) decode() (*T, error) {
+	value := new(T)
+	if err := w.l.Unmarshal(value, w.l.prefix); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (w *Watcher[T]) handleError(err error) {
+	if err == nil {
+		return
+	}
+	w.mutex.Lock()
+	onError := w.onError
+	w.mutex.Unlock()
+	if onError != nil {
+		onError(err)
+	}
+}
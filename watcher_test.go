@@ -0,0 +1,114 @@
+package lamenv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type watchedConfig struct {
+	Host   string            `yaml:"host,omitempty"`
+	Port   int               `yaml:"port"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	source := MapSource{
+		"APP_HOST":        "localhost",
+		"APP_PORT":        "8080",
+		"APP_LABELS_TEAM": "core",
+	}
+	w, err := NewWatcher[watchedConfig](WithSource(source), WithPrefix("app"))
+	assert.NoError(t, err)
+	first := w.Current()
+	assert.Equal(t, &watchedConfig{Host: "localhost", Port: 8080, Labels: map[string]string{"team": "core"}}, first)
+
+	var events []Event[watchedConfig]
+	w.Subscribe(func(event Event[watchedConfig]) {
+		events = append(events, event)
+	})
+
+	// nothing has changed, so nobody is notified
+	assert.NoError(t, w.Reload())
+	assert.Empty(t, events)
+	assert.Same(t, first, w.Current())
+
+	source["APP_PORT"] = "9090"
+	source["APP_LABELS_TIER"] = "1"
+	delete(source, "APP_HOST")
+	assert.NoError(t, w.Reload())
+	assert.Len(t, events, 1)
+	assert.Same(t, first, events[0].Previous)
+	assert.Same(t, w.Current(), events[0].Current)
+	assert.Equal(t, &watchedConfig{Port: 9090, Labels: map[string]string{"team": "core", "tier": "1"}}, w.Current())
+	assert.Equal(t, []VariableChange{
+		{Variable: "APP_HOST", Kind: Removed, Old: "localhost"},
+		{Variable: "APP_LABELS_TIER", Kind: Added, New: "1"},
+		{Variable: "APP_PORT", Kind: Modified, Old: "8080", New: "9090"},
+	}, events[0].Changes)
+
+	// the previous value is kept when the variables cannot be decoded
+	source["APP_PORT"] = "not a number"
+	assert.Error(t, w.Reload())
+	assert.Same(t, events[0].Current, w.Current())
+}
+
+func TestWatcher_SubscriberUsingTheWatcher(t *testing.T) {
+	source := MapSource{"APP_PORT": "8080"}
+	w, err := NewWatcher[watchedConfig](WithSource(source), WithPrefix("app"))
+	assert.NoError(t, err)
+	notified := 0
+	w.Subscribe(func(event Event[watchedConfig]) {
+		notified++
+		// none of these calls must block
+		w.OnError(nil).PollInterval(time.Second).Subscribe(func(Event[watchedConfig]) {})
+		assert.NoError(t, w.Reload())
+	})
+	done := make(chan error)
+	go func() {
+		source["APP_PORT"] = "9090"
+		done <- w.Reload()
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, 1, notified)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher is deadlocked")
+	}
+}
+
+func TestWatcher_WatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	now := time.Now()
+	writeDotEnv(t, path, "APP_HOST=localhost\n", now)
+	source, err := NewFileSource(path)
+	assert.NoError(t, err)
+	w, err := NewWatcher[watchedConfig](WithSource(source), WithPrefix("app"))
+	assert.NoError(t, err)
+	events := make(chan Event[watchedConfig], 1)
+	w.PollInterval(10 * time.Millisecond).Subscribe(func(event Event[watchedConfig]) {
+		events <- event
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(ctx)
+	}()
+
+	writeDotEnv(t, path, "APP_HOST=example.com\n", now.Add(time.Second))
+	select {
+	case event := <-events:
+		assert.Equal(t, []VariableChange{
+			{Variable: "APP_HOST", Kind: Modified, Old: "localhost", New: "example.com"},
+		}, event.Changes)
+		assert.Equal(t, "example.com", w.Current().Host)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the modification of the file has not been detected")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}